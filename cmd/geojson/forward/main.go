@@ -1,7 +1,15 @@
+// Command forward is kept for existing scripts and runs "nn predict" with the
+// same arguments.
+//
+// Deprecated: use the nn command.
 package main
 
-import "github.com/whyisemerald/neural_network/internal/app/geojson"
+import (
+	"os"
+
+	"github.com/whyisemerald/neural_network/internal/app/cli"
+)
 
 func main() {
-	geojson.Forward()
+	os.Exit(cli.Run(append([]string{"predict"}, os.Args[1:]...)))
 }
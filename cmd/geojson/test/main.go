@@ -1,7 +1,15 @@
+// Command test is kept for existing scripts and runs "nn test" with the
+// same arguments.
+//
+// Deprecated: use the nn command.
 package main
 
-import "github.com/whyisemerald/neural_network/internal/app/geojson"
+import (
+	"os"
+
+	"github.com/whyisemerald/neural_network/internal/app/cli"
+)
 
 func main() {
-	geojson.Test()
+	os.Exit(cli.Run(append([]string{"test"}, os.Args[1:]...)))
 }
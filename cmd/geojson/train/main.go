@@ -1,7 +1,15 @@
+// Command train is kept for existing scripts and runs "nn train" with the
+// same arguments.
+//
+// Deprecated: use the nn command.
 package main
 
-import "github.com/whyisemerald/neural_network/internal/app/geojson"
+import (
+	"os"
+
+	"github.com/whyisemerald/neural_network/internal/app/cli"
+)
 
 func main() {
-	geojson.Train()
+	os.Exit(cli.Run(append([]string{"train"}, os.Args[1:]...)))
 }
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/whyisemerald/neural_network/internal/app/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:]))
+}
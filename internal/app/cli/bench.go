@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/whyisemerald/neural_network/internal/app/geojson"
+	"github.com/whyisemerald/neural_network/internals/matrix"
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+var benchCommand = &command{
+	name:    "bench",
+	args:    "[matrix] [train] [index]",
+	summary: "run performance benchmarks",
+	help: `
+Bench runs the named benchmarks, or all of them:
+
+  matrix  times every matrix operation serially and in parallel and prints
+          the work size at which the parallel path starts to win
+  train   trains copies of one network with the sgd, data-parallel and
+          hogwild trainers and prints time and held-out accuracy per epoch
+  index   compares GeoJSON region lookup through the spatial index with a
+          linear scan over every polygon
+
+The train benchmark uses the training flags; keep -samples and -epochs
+small, e.g. -samples 20000 -epochs 5. The index benchmark looks up
+-test-count points, of which at most 10000 also go through the linear scan.`,
+	flags: dataFlags | csvFlags | trainFlags | testFlags,
+	run:   runBench,
+}
+
+// benchCalibrateWork is the largest work size the matrix benchmark tries.
+const benchCalibrateWork = 1 << 22
+
+// benchLinearPoints caps the points the slow linear scan is timed on.
+const benchLinearPoints = 10000
+
+func runBench(cfg Config, args []string) error {
+	benches := map[string]func(Config) error{
+		"matrix": benchMatrix,
+		"train":  benchTrain,
+		"index":  benchIndex,
+	}
+	if len(args) == 0 {
+		args = []string{"matrix", "train", "index"}
+	}
+	for _, name := range args {
+		if benches[name] == nil {
+			return usagef("unknown benchmark %q", name)
+		}
+	}
+	for i, name := range args {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		if err := benches[name](cfg); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func benchMatrix(Config) error {
+	fmt.Fprintf(stdout, "Matrix crossovers (%d pool workers)\n", matrix.Pool().Size())
+	fmt.Fprintf(stdout, "%-16s %12s %12s %12s\n", "op", "work", "serial", "parallel")
+	for _, c := range matrix.Calibrate(benchCalibrateWork) {
+		if !c.Found {
+			fmt.Fprintf(stdout, "%-16s %12s %12s %12s\n", c.Op, "none", "-", "-")
+			continue
+		}
+		fmt.Fprintf(stdout, "%-16s %12d %12s %12s\n", c.Op, c.Work, c.Serial, c.Parallel)
+	}
+	return nil
+}
+
+// benchTrain trains the same initial network with each trainer, holding out
+// a fifth of the data to measure convergence.
+func benchTrain(cfg Config) error {
+	data, err := loadTrainingSet(cfg)
+	if err != nil {
+		return err
+	}
+	split := len(data.inputs) * 4 / 5
+	if split == 0 || split == len(data.inputs) {
+		return fmt.Errorf("need at least 2 samples, got %d", len(data.inputs))
+	}
+	trainX, trainY := data.inputs[:split], data.labels[:split]
+	testX, testY := data.inputs[split:], data.labels[split:]
+
+	initial, err := newNetwork(&dataset{inputs: trainX, classes: data.classes, lower: data.lower, upper: data.upper}, cfg)
+	if err != nil {
+		return err
+	}
+
+	trainers := []struct {
+		name  string
+		epoch func(n *network.Network) func() error
+	}{
+		{"sgd", func(n *network.Network) func() error {
+			return func() error {
+				for i := range trainX {
+					if err := n.TrainClass(trainX[i], trainY[i], cfg.LearningRate); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}},
+		{"data-parallel", func(n *network.Network) func() error {
+			t := network.NewDataParallelTrainer(n, cfg.Workers)
+			return func() error {
+				for start := 0; start < len(trainX); start += cfg.BatchSize {
+					end := min(start+cfg.BatchSize, len(trainX))
+					if err := t.TrainBatchClasses(trainX[start:end], trainY[start:end], cfg.LearningRate); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}},
+		{"hogwild", func(n *network.Network) func() error {
+			t := network.NewHogwildTrainer(n, cfg.Workers)
+			return func() error {
+				return t.TrainEpochClasses(trainX, trainY, cfg.LearningRate)
+			}
+		}},
+	}
+
+	fmt.Fprintf(stdout, "Trainer convergence (%d train, %d held out, %d workers, batch %d)\n", len(trainX), len(testX), cfg.Workers, cfg.BatchSize)
+	fmt.Fprintf(stdout, "%-14s %6s %12s %12s %10s\n", "trainer", "epoch", "epoch time", "total", "accuracy")
+	for _, trainer := range trainers {
+		n := initial.Clone()
+		epoch := trainer.epoch(n)
+		var total time.Duration
+		for e := 1; e <= cfg.Epochs; e++ {
+			start := time.Now()
+			if err := epoch(); err != nil {
+				return err
+			}
+			took := time.Since(start)
+			total += took
+			predicted, err := n.PredictClasses(testX)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%-14s %6d %12s %12s %9.2f%%\n", trainer.name, e, took.Round(time.Microsecond), total.Round(time.Microsecond), accuracy(predicted, testY)*100)
+		}
+	}
+	return nil
+}
+
+func benchIndex(cfg Config) error {
+	r, err := loadRegions(cfg)
+	if err != nil {
+		return err
+	}
+	data := r.data
+	rng := rand.New(rand.NewSource(1))
+	points := make([]geojson.Point, cfg.TestCount)
+	for i := range points {
+		points[i] = geojson.Point{
+			data.MinLon + rng.Float64()*(data.MaxLon-data.MinLon),
+			data.MinLat + rng.Float64()*(data.MaxLat-data.MinLat),
+		}
+	}
+
+	found := make([]int, len(points))
+	start := time.Now()
+	for i, p := range points {
+		found[i], _ = data.Locate(p)
+	}
+	indexed := time.Since(start)
+
+	linearPoints := points[:min(len(points), benchLinearPoints)]
+	mismatches := 0
+	start = time.Now()
+	for i, p := range linearPoints {
+		if j, _ := data.LocateLinear(p); j != found[i] {
+			mismatches++
+		}
+	}
+	linear := time.Since(start)
+
+	perIndexed := indexed / time.Duration(len(points))
+	perLinear := linear / time.Duration(len(linearPoints))
+	fmt.Fprintf(stdout, "Region lookup (%d regions)\n", len(data.MultiPolygons))
+	fmt.Fprintf(stdout, "%-8s %10s %14s %12s\n", "method", "points", "total", "per point")
+	fmt.Fprintf(stdout, "%-8s %10d %14s %12s\n", "index", len(points), indexed.Round(time.Microsecond), perIndexed)
+	fmt.Fprintf(stdout, "%-8s %10d %14s %12s\n", "linear", len(linearPoints), linear.Round(time.Microsecond), perLinear)
+	if perIndexed > 0 {
+		fmt.Fprintf(stdout, "Speedup: %.1fx, mismatches: %d\n", float64(perLinear)/float64(perIndexed), mismatches)
+	}
+	return nil
+}
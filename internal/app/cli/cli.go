@@ -0,0 +1,177 @@
+// Package cli implements the nn command: one binary whose subcommands train,
+// test and serve networks on GeoJSON regions or on generic matrix datasets.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// command is one subcommand of nn.
+type command struct {
+	name    string
+	args    string
+	summary string
+	help    string
+	flags   flagGroup
+	run     func(cfg Config, args []string) error
+}
+
+// usageError is returned by a subcommand for bad positional arguments, so
+// Run can print the subcommand's usage.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+func commands() []*command {
+	return []*command{
+		trainCommand,
+		testCommand,
+		predictCommand,
+		inspectCommand,
+		convertCommand,
+		serveCommand,
+		benchCommand,
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit
+// code: 0 on success, 1 when the command failed and 2 for usage errors.
+func Run(args []string) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	case "help":
+		if len(args) < 2 {
+			usage(stdout)
+			return 0
+		}
+		cmd := findCommand(args[1])
+		if cmd == nil {
+			fmt.Fprintf(stderr, "nn: unknown command %q\n", args[1])
+			return 2
+		}
+		fs := flag.NewFlagSet("nn "+cmd.name, flag.ContinueOnError)
+		fs.SetOutput(stdout)
+		cfg := DefaultConfig()
+		fs.String("config", "", "JSON config file (env NN_CONFIG)")
+		cfg.RegisterFlags(fs, cmd.flags)
+		cmd.usage(fs)
+		return 0
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "nn: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	cfg, rest, err := loadConfig(cmd, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "nn %s: %v\n", cmd.name, err)
+		return 2
+	}
+	if err := setupLogging(cfg); err != nil {
+		fmt.Fprintf(stderr, "nn %s: %v\n", cmd.name, err)
+		return 2
+	}
+
+	if err := cmd.run(cfg, rest); err != nil {
+		var ue *usageError
+		if errors.As(err, &ue) {
+			fmt.Fprintf(stderr, "nn %s: %v\nRun 'nn help %s' for usage.\n", cmd.name, err, cmd.name)
+			return 2
+		}
+		slog.Error(cmd.name+" failed", "err", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "nn trains and runs neural network classifiers.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  nn <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every command reads an optional JSON config file (-config or NN_CONFIG)")
+	fmt.Fprintln(w, "and NN_* environment variables; flags take precedence over both.")
+	fmt.Fprintln(w, "Run 'nn help <command>' or 'nn <command> -help' for details.")
+}
+
+func (cmd *command) usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: nn %s [flags]", cmd.name)
+	if cmd.args != "" {
+		fmt.Fprintf(w, " %s", cmd.args)
+	}
+	fmt.Fprintf(w, "\n\n%s\n\nFlags:\n", strings.TrimSpace(cmd.help))
+	fs.PrintDefaults()
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// setupLogging points the default slog logger at stderr with the configured
+// level and format. Command results go to stdout.
+func setupLogging(cfg Config) error {
+	level, err := parseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -0,0 +1,475 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/whyisemerald/neural_network/internal/app/geojson"
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+// Config holds the settings shared by the subcommands. Values are layered:
+// DefaultConfig, then a JSON config file, then NN_* environment variables,
+// then command line flags.
+type Config struct {
+	// GeojsonPath is the GeoJSON file whose regions form the classes
+	GeojsonPath string `json:"geojson_path"`
+	// Data is a CSV, npy, npz or bin dataset used instead of the GeoJSON
+	// regions. Every row is a sample and LabelColumn holds its class.
+	Data string `json:"data"`
+	// TestData is the dataset test evaluates on. It defaults to Data.
+	TestData string `json:"test_data"`
+	// LabelColumn is the column of Data holding the class, negative values
+	// counting from the end
+	LabelColumn int `json:"label_column"`
+	// Header skips the first line of CSV datasets
+	Header bool `json:"header"`
+	// ModelPath is the path to save the trained model
+	ModelPath string `json:"model_path"`
+	// NumSamples is the number of points to generate for training
+	NumSamples int `json:"num_samples"`
+	// LearningRate is the learning rate for training
+	LearningRate float64 `json:"learning_rate"`
+	// Epochs is the number of training cycles
+	Epochs int `json:"epochs"`
+	// TestCount is the number of test points to generate for accuracy testing
+	TestCount int `json:"test_count"`
+	// HiddenLayerSizes is the size of each hidden layer
+	HiddenLayerSizes []int `json:"hidden_layer_sizes"`
+	// LabelProperties are the feature properties tried in order to name a class
+	LabelProperties []string `json:"label_properties"`
+	// GroupLabels merges features with the same label into one class
+	GroupLabels bool `json:"group_labels"`
+	// Sampling is uniform, stratified, area or boundary, how points are
+	// drawn from the GeoJSON regions
+	Sampling string `json:"sampling"`
+	// BoundaryFraction is the fraction of boundary samples drawn near edges
+	BoundaryFraction float64 `json:"boundary_fraction"`
+	// BoundaryWidth is the spread in degrees of boundary samples around edges
+	BoundaryWidth float64 `json:"boundary_width"`
+	// MaxAttempts is the number of candidate points drawn for one sample
+	// before sampling fails
+	MaxAttempts int `json:"max_attempts"`
+	// Background is the fraction of GeoJSON samples drawn outside every
+	// region and labelled with an extra "none" class. Zero disables it.
+	Background float64 `json:"background"`
+	// Margin is how far in degrees background samples may lie outside the
+	// bounding box of the regions
+	Margin float64 `json:"margin"`
+	// Normalize is none, minmax, standard or bounds, the input normalizer
+	// fitted when a new model is created
+	Normalize string `json:"normalize"`
+	// Encoding is none, sincos or fourier, the feature encoding of the
+	// inputs of new models
+	Encoding string `json:"encoding"`
+	// Frequencies is the number of sincos octaves or fourier frequencies
+	Frequencies int `json:"frequencies"`
+	// FourierScale is the standard deviation of the fourier frequencies
+	FourierScale float64 `json:"fourier_scale"`
+	// Sphere adds the unit sphere position of the longitude and latitude
+	// to the features of new models
+	Sphere bool `json:"sphere"`
+	// Trainer is sgd, data-parallel or hogwild
+	Trainer string `json:"trainer"`
+	// Workers is the number of replicas or goroutines of the parallel trainers
+	Workers int `json:"workers"`
+	// BatchSize is the mini-batch size of the data-parallel trainer
+	BatchSize int `json:"batch_size"`
+	// Addr is the listen address of serve
+	Addr string `json:"addr"`
+	// LogLevel is debug, info, warn or error
+	LogLevel string `json:"log_level"`
+	// LogFormat is text or json
+	LogFormat string `json:"log_format"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		GeojsonPath:      "data/INDIA/india.geojson",
+		LabelColumn:      -1,
+		ModelPath:        "models/india.json",
+		NumSamples:       500000,
+		LearningRate:     0.1,
+		Epochs:           500,
+		TestCount:        1000000,
+		HiddenLayerSizes: []int{20, 35, 20},
+		LabelProperties:  append([]string(nil), geojson.DefaultLabelOptions.Properties...),
+		Sampling:         geojson.DefaultSampleOptions.Strategy,
+		BoundaryFraction: geojson.DefaultSampleOptions.BoundaryFraction,
+		BoundaryWidth:    geojson.DefaultSampleOptions.BoundaryWidth,
+		MaxAttempts:      geojson.DefaultSampleOptions.MaxAttempts,
+		Margin:           geojson.DefaultSampleOptions.Margin,
+		Normalize:        network.NormalizeMinMax,
+		Encoding:         encodeNone,
+		Frequencies:      8,
+		FourierScale:     4,
+		Trainer:          "sgd",
+		Workers:          runtime.NumCPU(),
+		BatchSize:        32,
+		Addr:             "localhost:8080",
+		LogLevel:         "info",
+		LogFormat:        "text",
+	}
+}
+
+// Labels returns the label options described by the config.
+func (c *Config) Labels() geojson.LabelOptions {
+	return geojson.LabelOptions{Properties: c.LabelProperties, Group: c.GroupLabels}
+}
+
+// SampleOptions returns the sampling options described by the config, with
+// strata for the given class of every region.
+func (c *Config) SampleOptions(regionClass []int) geojson.SampleOptions {
+	return geojson.SampleOptions{
+		Strategy:         c.Sampling,
+		Strata:           regionClass,
+		BoundaryFraction: c.BoundaryFraction,
+		BoundaryWidth:    c.BoundaryWidth,
+		MaxAttempts:      c.MaxAttempts,
+		Background:       c.Background,
+		Margin:           c.Margin,
+	}
+}
+
+// flagGroup selects which settings a subcommand exposes as flags.
+type flagGroup int
+
+const (
+	dataFlags flagGroup = 1 << iota
+	csvFlags
+	modelFlags
+	trainFlags
+	testFlags
+	serveFlags
+)
+
+// loadConfig builds the config for a subcommand from its arguments, reading
+// the config file named by -config or NN_CONFIG if there is one. It returns
+// the positional arguments, and flag.ErrHelp when -h or -help was given.
+func loadConfig(cmd *command, args []string) (Config, []string, error) {
+	c := DefaultConfig()
+
+	path := configPathFromArgs(args)
+	if path == "" {
+		path = os.Getenv("NN_CONFIG")
+	}
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return c, nil, err
+		}
+	}
+	if err := c.ApplyEnv(os.LookupEnv); err != nil {
+		return c, nil, err
+	}
+
+	fs := flag.NewFlagSet("nn "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() { cmd.usage(fs) }
+	fs.String("config", path, "JSON config file (env NN_CONFIG)")
+	c.RegisterFlags(fs, cmd.flags)
+	if err := fs.Parse(args); err != nil {
+		return c, nil, err
+	}
+	return c, fs.Args(), c.Validate()
+}
+
+// configPathFromArgs finds the -config flag ahead of the real parse, since
+// the file has to be applied before the other flags override it.
+func configPathFromArgs(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// LoadFile overlays the settings present in a JSON config file. Unknown
+// keys are an error so that typos do not go unnoticed.
+func (c *Config) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overlays the NN_* variables found by lookup.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	parse := func(name string, set func(string) error) {
+		if v, ok := lookup(name); ok {
+			if err := set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	str("NN_GEOJSON_PATH", &c.GeojsonPath)
+	str("NN_DATA", &c.Data)
+	str("NN_TEST_DATA", &c.TestData)
+	parse("NN_LABEL_COLUMN", (*intValue)(&c.LabelColumn).Set)
+	parse("NN_HEADER", (*boolValue)(&c.Header).Set)
+	str("NN_MODEL_PATH", &c.ModelPath)
+	parse("NN_NUM_SAMPLES", (*intValue)(&c.NumSamples).Set)
+	parse("NN_LEARNING_RATE", (*floatValue)(&c.LearningRate).Set)
+	parse("NN_EPOCHS", (*intValue)(&c.Epochs).Set)
+	parse("NN_TEST_COUNT", (*intValue)(&c.TestCount).Set)
+	parse("NN_HIDDEN_LAYERS", (*intList)(&c.HiddenLayerSizes).Set)
+	parse("NN_LABEL_PROPERTIES", (*stringList)(&c.LabelProperties).Set)
+	parse("NN_GROUP_LABELS", (*boolValue)(&c.GroupLabels).Set)
+	str("NN_SAMPLING", &c.Sampling)
+	parse("NN_BOUNDARY_FRACTION", (*floatValue)(&c.BoundaryFraction).Set)
+	parse("NN_BOUNDARY_WIDTH", (*floatValue)(&c.BoundaryWidth).Set)
+	parse("NN_MAX_ATTEMPTS", (*intValue)(&c.MaxAttempts).Set)
+	parse("NN_BACKGROUND", (*floatValue)(&c.Background).Set)
+	parse("NN_MARGIN", (*floatValue)(&c.Margin).Set)
+	str("NN_NORMALIZE", &c.Normalize)
+	str("NN_ENCODING", &c.Encoding)
+	parse("NN_FREQUENCIES", (*intValue)(&c.Frequencies).Set)
+	parse("NN_FOURIER_SCALE", (*floatValue)(&c.FourierScale).Set)
+	parse("NN_SPHERE", (*boolValue)(&c.Sphere).Set)
+	str("NN_TRAINER", &c.Trainer)
+	parse("NN_WORKERS", (*intValue)(&c.Workers).Set)
+	parse("NN_BATCH_SIZE", (*intValue)(&c.BatchSize).Set)
+	str("NN_ADDR", &c.Addr)
+	str("NN_LOG_LEVEL", &c.LogLevel)
+	str("NN_LOG_FORMAT", &c.LogFormat)
+	return errors.Join(errs...)
+}
+
+// RegisterFlags adds a flag for every setting in groups, defaulting to the
+// current values. The logging flags are always added.
+func (c *Config) RegisterFlags(fs *flag.FlagSet, groups flagGroup) {
+	if groups&dataFlags != 0 {
+		fs.StringVar(&c.GeojsonPath, "geojson", c.GeojsonPath, "GeoJSON file with the regions (env NN_GEOJSON_PATH)")
+		fs.StringVar(&c.Data, "data", c.Data, "CSV, npy, npz or bin dataset to use instead of the GeoJSON regions (env NN_DATA)")
+		fs.IntVar(&c.LabelColumn, "label-col", c.LabelColumn, "column of -data holding the class, negative counts from the end (env NN_LABEL_COLUMN)")
+		fs.Var((*stringList)(&c.LabelProperties), "label", "comma separated feature properties naming each class (env NN_LABEL_PROPERTIES)")
+		fs.BoolVar(&c.GroupLabels, "group", c.GroupLabels, "merge features with the same label into one class (env NN_GROUP_LABELS)")
+		fs.StringVar(&c.Sampling, "sampling", c.Sampling, "how GeoJSON points are drawn: uniform, stratified, area or boundary (env NN_SAMPLING)")
+		fs.Float64Var(&c.BoundaryFraction, "boundary-fraction", c.BoundaryFraction, "fraction of boundary sampling drawn near edges (env NN_BOUNDARY_FRACTION)")
+		fs.Float64Var(&c.BoundaryWidth, "boundary-width", c.BoundaryWidth, "spread in degrees of boundary samples around edges (env NN_BOUNDARY_WIDTH)")
+		fs.IntVar(&c.MaxAttempts, "max-attempts", c.MaxAttempts, "candidate points drawn for one sample before sampling fails (env NN_MAX_ATTEMPTS)")
+		fs.Float64Var(&c.Background, "background", c.Background, "fraction of GeoJSON points drawn outside every region as class \"none\" (env NN_BACKGROUND)")
+		fs.Float64Var(&c.Margin, "margin", c.Margin, "degrees around the regions' bounding box for background points (env NN_MARGIN)")
+	}
+	if groups&csvFlags != 0 {
+		fs.BoolVar(&c.Header, "header", c.Header, "CSV datasets start with a header line (env NN_HEADER)")
+	}
+	if groups&modelFlags != 0 {
+		fs.StringVar(&c.ModelPath, "model", c.ModelPath, "model file (env NN_MODEL_PATH)")
+	}
+	if groups&trainFlags != 0 {
+		fs.IntVar(&c.NumSamples, "samples", c.NumSamples, "number of GeoJSON training points (env NN_NUM_SAMPLES)")
+		fs.Float64Var(&c.LearningRate, "lr", c.LearningRate, "learning rate (env NN_LEARNING_RATE)")
+		fs.IntVar(&c.Epochs, "epochs", c.Epochs, "number of training epochs (env NN_EPOCHS)")
+		fs.Var((*intList)(&c.HiddenLayerSizes), "hidden", "comma separated hidden layer sizes (env NN_HIDDEN_LAYERS)")
+		fs.StringVar(&c.Normalize, "normalize", c.Normalize, "input normalization of new models: none, minmax, standard or bounds (env NN_NORMALIZE)")
+		fs.StringVar(&c.Encoding, "encoding", c.Encoding, "input feature encoding of new models: none, sincos or fourier (env NN_ENCODING)")
+		fs.IntVar(&c.Frequencies, "frequencies", c.Frequencies, "number of sincos octaves or fourier frequencies (env NN_FREQUENCIES)")
+		fs.Float64Var(&c.FourierScale, "fourier-scale", c.FourierScale, "standard deviation of the fourier frequencies (env NN_FOURIER_SCALE)")
+		fs.BoolVar(&c.Sphere, "sphere", c.Sphere, "add unit sphere coordinates of the longitude and latitude to new models (env NN_SPHERE)")
+		fs.StringVar(&c.Trainer, "trainer", c.Trainer, "sgd, data-parallel or hogwild (env NN_TRAINER)")
+		fs.IntVar(&c.Workers, "workers", c.Workers, "workers of the parallel trainers (env NN_WORKERS)")
+		fs.IntVar(&c.BatchSize, "batch", c.BatchSize, "mini-batch size of the data-parallel trainer (env NN_BATCH_SIZE)")
+	}
+	if groups&testFlags != 0 {
+		fs.IntVar(&c.TestCount, "test-count", c.TestCount, "number of GeoJSON test points (env NN_TEST_COUNT)")
+		fs.StringVar(&c.TestData, "test-data", c.TestData, "dataset to test on, defaults to -data (env NN_TEST_DATA)")
+	}
+	if groups&serveFlags != 0 {
+		fs.StringVar(&c.Addr, "addr", c.Addr, "listen address (env NN_ADDR)")
+	}
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "debug, info, warn or error (env NN_LOG_LEVEL)")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "text or json (env NN_LOG_FORMAT)")
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if c.GeojsonPath == "" && c.Data == "" {
+		errs = append(errs, errors.New("either a geojson path or a dataset must be set"))
+	}
+	if c.ModelPath == "" {
+		errs = append(errs, errors.New("model path must be set"))
+	}
+	if c.NumSamples <= 0 {
+		errs = append(errs, fmt.Errorf("number of samples must be positive, got %d", c.NumSamples))
+	}
+	if !(c.LearningRate > 0) || math.IsInf(c.LearningRate, 0) {
+		errs = append(errs, fmt.Errorf("learning rate must be a positive number, got %v", c.LearningRate))
+	}
+	if c.Epochs <= 0 {
+		errs = append(errs, fmt.Errorf("epochs must be positive, got %d", c.Epochs))
+	}
+	if c.TestCount <= 0 {
+		errs = append(errs, fmt.Errorf("test count must be positive, got %d", c.TestCount))
+	}
+	for i, size := range c.HiddenLayerSizes {
+		if size <= 0 {
+			errs = append(errs, fmt.Errorf("hidden layer %d size must be positive, got %d", i, size))
+		}
+	}
+	if len(c.LabelProperties) == 0 {
+		errs = append(errs, errors.New("at least one label property is required"))
+	}
+	switch c.Sampling {
+	case geojson.SampleUniform, geojson.SampleStratified, geojson.SampleArea, geojson.SampleBoundary:
+	default:
+		errs = append(errs, fmt.Errorf("unknown sampling strategy %q", c.Sampling))
+	}
+	if !(c.BoundaryFraction >= 0 && c.BoundaryFraction <= 1) {
+		errs = append(errs, fmt.Errorf("boundary fraction must be within [0, 1], got %v", c.BoundaryFraction))
+	}
+	if !(c.BoundaryWidth > 0) || math.IsInf(c.BoundaryWidth, 0) {
+		errs = append(errs, fmt.Errorf("boundary width must be a positive number, got %v", c.BoundaryWidth))
+	}
+	if c.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("max attempts must be positive, got %d", c.MaxAttempts))
+	}
+	if !(c.Background >= 0 && c.Background <= 1) {
+		errs = append(errs, fmt.Errorf("background fraction must be within [0, 1], got %v", c.Background))
+	}
+	if !(c.Margin >= 0) || math.IsInf(c.Margin, 0) {
+		errs = append(errs, fmt.Errorf("margin must be a non-negative number, got %v", c.Margin))
+	}
+	switch c.Normalize {
+	case normalizeNone, network.NormalizeMinMax, network.NormalizeStandard, network.NormalizeBounds:
+	default:
+		errs = append(errs, fmt.Errorf("unknown normalization %q", c.Normalize))
+	}
+	switch c.Encoding {
+	case encodeNone, network.EncodeSinCos, network.EncodeFourier:
+	default:
+		errs = append(errs, fmt.Errorf("unknown encoding %q", c.Encoding))
+	}
+	if c.Frequencies <= 0 {
+		errs = append(errs, fmt.Errorf("frequencies must be positive, got %d", c.Frequencies))
+	}
+	if !(c.FourierScale > 0) || math.IsInf(c.FourierScale, 0) {
+		errs = append(errs, fmt.Errorf("fourier scale must be a positive number, got %v", c.FourierScale))
+	}
+	switch c.Trainer {
+	case "sgd", "data-parallel", "hogwild":
+	default:
+		errs = append(errs, fmt.Errorf("unknown trainer %q", c.Trainer))
+	}
+	if c.Workers <= 0 {
+		errs = append(errs, fmt.Errorf("workers must be positive, got %d", c.Workers))
+	}
+	if c.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch size must be positive, got %d", c.BatchSize))
+	}
+	if _, err := parseLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("unknown log format %q", c.LogFormat))
+	}
+	return errors.Join(errs...)
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	*v = intValue(n)
+	return err
+}
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	*v = floatValue(f)
+	return err
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	*v = boolValue(b)
+	return err
+}
+
+// intList is a flag.Value for comma separated integers. An empty string is
+// an empty list.
+type intList []int
+
+func (l *intList) String() string {
+	if l == nil {
+		return ""
+	}
+	parts := make([]string, len(*l))
+	for i, n := range *l {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(s string) error {
+	list := intList{}
+	for _, part := range splitList(s) {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return err
+		}
+		list = append(list, n)
+	}
+	*l = list
+	return nil
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = splitList(s)
+	return nil
+}
+
+func splitList(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
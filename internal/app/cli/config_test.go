@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"epochs": 10,
+		"learning_rate": 0.5,
+		"batch_size": 7,
+		"hidden_layer_sizes": [4, 4]
+	}`)
+	t.Setenv("NN_EPOCHS", "20")
+	t.Setenv("NN_LEARNING_RATE", "0.25")
+
+	cfg, args, err := loadConfig(trainCommand, []string{"-config", path, "-epochs", "30", "-hidden", "8", "extra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Epochs != 30 {
+		t.Errorf("epochs = %d, want the flag's 30", cfg.Epochs)
+	}
+	if cfg.LearningRate != 0.25 {
+		t.Errorf("learning rate = %v, want NN_LEARNING_RATE's 0.25", cfg.LearningRate)
+	}
+	if cfg.BatchSize != 7 {
+		t.Errorf("batch size = %d, want the file's 7", cfg.BatchSize)
+	}
+	if !slices.Equal(cfg.HiddenLayerSizes, []int{8}) {
+		t.Errorf("hidden layers = %v, want the flag's [8]", cfg.HiddenLayerSizes)
+	}
+	if cfg.NumSamples != DefaultConfig().NumSamples {
+		t.Errorf("samples = %d, want the default %d", cfg.NumSamples, DefaultConfig().NumSamples)
+	}
+	if !slices.Equal(args, []string{"extra"}) {
+		t.Errorf("args = %v", args)
+	}
+
+	// Without the flag the environment wins over the file.
+	cfg, _, err = loadConfig(trainCommand, []string{"-config=" + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Epochs != 20 {
+		t.Errorf("epochs = %d, want NN_EPOCHS's 20", cfg.Epochs)
+	}
+}
+
+func TestConfigFromEnvPath(t *testing.T) {
+	t.Setenv("NN_CONFIG", writeConfig(t, `{"epochs": 12}`))
+	cfg, _, err := loadConfig(trainCommand, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Epochs != 12 {
+		t.Errorf("epochs = %d, want 12 from the NN_CONFIG file", cfg.Epochs)
+	}
+}
+
+func TestConfigRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown key", file: `{"epoch": 5}`, want: "unknown field"},
+		{name: "malformed file", file: `{"epochs": }`, want: "failed to parse config"},
+		{name: "wrong type", file: `{"epochs": "many"}`, want: "failed to parse config"},
+		{name: "missing file", args: []string{"-config", "/nonexistent/config.json"}, want: "failed to read config"},
+		{name: "zero epochs", file: `{"epochs": 0}`, want: "epochs must be positive"},
+		{name: "negative hidden layer", file: `{"hidden_layer_sizes": [4, -1]}`, want: "hidden layer 1"},
+		{name: "unknown sampling", file: `{"sampling": "everywhere"}`, want: "unknown sampling strategy"},
+		{name: "background above one", file: `{"background": 1.5}`, want: "background fraction"},
+		{name: "unknown trainer", file: `{"trainer": "magic"}`, want: "unknown trainer"},
+		{name: "unknown log format", file: `{"log_format": "xml"}`, want: "unknown log format"},
+		{name: "bad env value", env: map[string]string{"NN_EPOCHS": "ten"}, want: "NN_EPOCHS"},
+		{name: "invalid env value", env: map[string]string{"NN_LEARNING_RATE": "-1"}, want: "learning rate"},
+		{name: "invalid flag value", args: []string{"-batch", "0"}, want: "batch size"},
+		{name: "unknown flag", args: []string{"-no-such-flag"}, want: "not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfig(t, tt.file)}, args...)
+			}
+			_, _, err := loadConfig(trainCommand, args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("loadConfig error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareModelPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "model.json")
+	if err := prepareModelPath(path); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("prepareModelPath left %d files behind", len(entries))
+	}
+
+	if err := prepareModelPath(dir); err == nil {
+		t.Error("prepareModelPath accepted a directory as the model file")
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareModelPath(filepath.Join(file, "model.json")); err == nil {
+		t.Error("prepareModelPath accepted a model under a regular file")
+	}
+}
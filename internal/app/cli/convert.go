@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"log/slog"
+
+	"github.com/whyisemerald/neural_network/internals/matrix"
+)
+
+var convertCommand = &command{
+	name:    "convert",
+	args:    "<input> <output>",
+	summary: "convert a dataset between CSV, npy, npz and bin",
+	help: `
+Convert reads a matrix from input and writes it to output, choosing both
+formats from the file extensions: .csv, .npy, .npz or .bin. Use -header
+when a CSV input starts with column names.`,
+	flags: csvFlags,
+	run:   runConvert,
+}
+
+func runConvert(cfg Config, args []string) error {
+	if len(args) != 2 {
+		return usagef("want an input and an output file, got %d arguments", len(args))
+	}
+	m, err := readMatrix(args[0], cfg)
+	if err != nil {
+		return err
+	}
+	if err := matrix.WriteFile(args[1], m); err != nil {
+		return err
+	}
+	slog.Info("converted", "from", args[0], "to", args[1], "rows", m.Rows, "cols", m.Cols)
+	return nil
+}
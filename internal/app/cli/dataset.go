@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/whyisemerald/neural_network/internal/app/geojson"
+	"github.com/whyisemerald/neural_network/internals/matrix"
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+// dataset is a set of labelled samples and the names of the classes the
+// labels index. Samples drawn from GeoJSON regions also carry the bounds of
+// the map they were drawn from.
+type dataset struct {
+	inputs       [][]float64
+	labels       []int
+	classes      []string
+	lower, upper []float64
+}
+
+// regions is a loaded GeoJSON file together with its class assignment and
+// the sampler drawing points from it.
+type regions struct {
+	data        *geojson.ExtractedGeoJSON
+	classes     []string
+	regionClass []int
+	sampler     *geojson.Sampler
+	// background is the class of points outside every region, or -1.
+	background int
+}
+
+// backgroundClass names the class of points outside every region.
+const backgroundClass = "none"
+
+func loadRegions(cfg Config) (*regions, error) {
+	data, err := geojson.LoadAndExtractGeoJSON(cfg.GeojsonPath)
+	if err != nil {
+		return nil, err
+	}
+	classes, regionClass := data.Classes(cfg.Labels())
+	// Stratified sampling gives every class, not every region, an equal
+	// share.
+	sampler, err := geojson.NewSampler(data, cfg.SampleOptions(regionClass))
+	if err != nil {
+		return nil, err
+	}
+	// The name is reserved even without background samples: hasBackground
+	// tells a model's background class from a region only by its name.
+	if slices.Contains(classes, backgroundClass) {
+		return nil, fmt.Errorf("a region is labelled %q, which is reserved for the background class", backgroundClass)
+	}
+	background := -1
+	if cfg.Background > 0 {
+		background = len(classes)
+		classes = append(classes[:len(classes):len(classes)], backgroundClass)
+	}
+	slog.Info("loaded regions", "path", cfg.GeojsonPath, "regions", len(data.MultiPolygons), "classes", len(classes), "sampling", cfg.Sampling)
+	return &regions{data: data, classes: classes, regionClass: regionClass, sampler: sampler, background: background}, nil
+}
+
+// sample draws n labelled points inside the regions, in parallel chunks.
+func (r *regions) sample(n int) (*dataset, error) {
+	d := &dataset{
+		inputs:  make([][]float64, n),
+		labels:  make([]int, n),
+		classes: r.classes,
+	}
+	bounds := r.sampler.Bounds()
+	d.lower = []float64{bounds.MinLon, bounds.MinLat}
+	d.upper = []float64{bounds.MaxLon, bounds.MaxLat}
+	var mu sync.Mutex
+	var errs []error
+	err := routines.ParallelFor(n, 1024, func(start, end int) {
+		inputs, labels, err := r.sampler.Sample(end-start, rand.New(rand.NewSource(rand.Int63())))
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			return
+		}
+		copy(d.inputs[start:end], inputs)
+		for i, region := range labels {
+			if region == geojson.BackgroundRegion {
+				d.labels[start+i] = r.background
+				continue
+			}
+			d.labels[start+i] = r.regionClass[region]
+		}
+	}, routines.WithSchedule(routines.Dynamic), routines.WithLabel("cli.sample"))
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("sampling failed: %w", errs[0])
+	}
+	return d, nil
+}
+
+// loadTrainingSet returns cfg.Data if set, or cfg.NumSamples points drawn
+// from the GeoJSON regions.
+func loadTrainingSet(cfg Config) (*dataset, error) {
+	if cfg.Data != "" {
+		return loadTable(cfg.Data, cfg, nil)
+	}
+	r, err := loadRegions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return r.sample(cfg.NumSamples)
+}
+
+// readMatrix reads a dataset file, honouring cfg.Header for CSV.
+func readMatrix(path string, cfg Config) (*matrix.Matrix, error) {
+	if !cfg.Header || strings.ToLower(filepath.Ext(path)) != ".csv" {
+		return matrix.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, _, err := matrix.ReadCSV(f, true)
+	return m, err
+}
+
+// loadTable reads a matrix dataset and splits off its label column. The
+// label values name the classes. When classes is nil they are discovered
+// and sorted numerically; otherwise every label must be one of them.
+func loadTable(path string, cfg Config, classes []string) (*dataset, error) {
+	m, err := readMatrix(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	col := cfg.LabelColumn
+	if col < 0 {
+		col += m.Cols
+	}
+	if col < 0 || col >= m.Cols {
+		return nil, fmt.Errorf("label column %d is out of range for %d columns", cfg.LabelColumn, m.Cols)
+	}
+	if m.Cols < 2 {
+		return nil, fmt.Errorf("%s needs at least one input column besides the label", path)
+	}
+
+	if classes == nil {
+		values := make([]float64, 0, m.Rows)
+		for i := 0; i < m.Rows; i++ {
+			values = append(values, m.Data[i*m.Cols+col])
+		}
+		slices.Sort(values)
+		for _, v := range slices.Compact(values) {
+			classes = append(classes, formatLabel(v))
+		}
+	}
+	classIndex := make(map[string]int, len(classes))
+	for i, name := range classes {
+		classIndex[name] = i
+	}
+
+	d := &dataset{
+		inputs:  make([][]float64, m.Rows),
+		labels:  make([]int, m.Rows),
+		classes: classes,
+	}
+	for i := 0; i < m.Rows; i++ {
+		row := m.Data[i*m.Cols : (i+1)*m.Cols]
+		label := formatLabel(row[col])
+		class, ok := classIndex[label]
+		if !ok {
+			return nil, fmt.Errorf("%s row %d: label %s is not one of the model classes", path, i, label)
+		}
+		d.labels[i] = class
+		d.inputs[i] = append(append(make([]float64, 0, m.Cols-1), row[:col]...), row[col+1:]...)
+	}
+	slog.Info("loaded dataset", "path", path, "samples", m.Rows, "inputs", m.Cols-1, "classes", len(classes))
+	return d, nil
+}
+
+func formatLabel(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// accuracy is the fraction of inputs the predictor assigns their label.
+func accuracy(predicted, labels []int) float64 {
+	if len(labels) == 0 {
+		return 0
+	}
+	correct := 0
+	for i := range labels {
+		if predicted[i] == labels[i] {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(labels))
+}
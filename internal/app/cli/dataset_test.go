@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whyisemerald/neural_network/internal/app/geojson"
+)
+
+// writeRegions writes a GeoJSON file of unit squares side by side, one per
+// name, and returns a config that samples it.
+func writeRegions(t *testing.T, names ...string) Config {
+	t.Helper()
+	features := make([]string, len(names))
+	for i, name := range names {
+		x := 2 * i
+		features[i] = fmt.Sprintf(`{"type":"Feature","properties":{"name":%q},"geometry":{"type":"Polygon","coordinates":[[[%d,0],[%d,0],[%d,1],[%d,1],[%d,0]]]}}`,
+			name, x, x+1, x+1, x, x)
+	}
+	path := filepath.Join(t.TempDir(), "regions.geojson")
+	body := `{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.GeojsonPath = path
+	cfg.Sampling = geojson.SampleStratified
+	return cfg
+}
+
+func TestLoadRegionsBackground(t *testing.T) {
+	cfg := writeRegions(t, "a", "b")
+	cfg.Background = 0.25
+
+	r, err := loadRegions(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", backgroundClass}; !slices.Equal(r.classes, want) {
+		t.Fatalf("classes = %v, want %v", r.classes, want)
+	}
+	if !hasBackground(r.classes) || r.background != 2 {
+		t.Fatalf("background class = %d, want 2", r.background)
+	}
+
+	d, err := r.sample(400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]int, len(r.classes))
+	for i, label := range d.labels {
+		counts[label]++
+		want := r.background
+		if region, ok := r.data.Locate(geojson.Point(d.inputs[i])); ok {
+			want = r.regionClass[region]
+		}
+		if label != want {
+			t.Fatalf("point %v is labelled %d, want %d", d.inputs[i], label, want)
+		}
+	}
+	if want := []int{150, 150, 100}; !slices.Equal(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
+func TestLoadRegionsWithoutBackground(t *testing.T) {
+	r, err := loadRegions(writeRegions(t, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.classes, []string{"a", "b"}) || hasBackground(r.classes) || r.background != -1 {
+		t.Errorf("classes = %v with background %d, want [a b] and none", r.classes, r.background)
+	}
+}
+
+func TestLoadRegionsReservesBackgroundName(t *testing.T) {
+	for _, background := range []float64{0, 0.25} {
+		cfg := writeRegions(t, "a", backgroundClass)
+		cfg.Background = background
+		_, err := loadRegions(cfg)
+		if err == nil || !strings.Contains(err.Error(), "reserved for the background class") {
+			t.Errorf("background %v: loadRegions() error = %v", background, err)
+		}
+	}
+}
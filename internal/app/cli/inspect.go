@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+var inspectCommand = &command{
+	name:    "inspect",
+	summary: "describe a saved model",
+	help: `
+Inspect prints the layer sizes, parameter count, input normalization and
+encoding and class names of the model at -model, and summary statistics of
+every layer's weights and biases.`,
+	flags: modelFlags,
+	run:   runInspect,
+}
+
+func runInspect(cfg Config, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %v", args)
+	}
+	n, err := network.Load(cfg.ModelPath)
+	if err != nil {
+		return err
+	}
+
+	sizes := n.GetLayerSizes()
+	parts := make([]string, len(sizes))
+	for i, size := range sizes {
+		parts[i] = strconv.Itoa(size)
+	}
+	parameters := 0
+	for _, layer := range n.Layers {
+		parameters += len(layer.Weights.Data) + len(layer.Biases.Data)
+	}
+
+	w := stdout
+	fmt.Fprintf(w, "Model:      %s\n", cfg.ModelPath)
+	fmt.Fprintf(w, "Layers:     %s\n", strings.Join(parts, " -> "))
+	fmt.Fprintf(w, "Parameters: %d\n", parameters)
+	fmt.Fprintf(w, "Normalize:  %s\n", normalizerKind(n.Normalizer))
+	if z := n.Normalizer; z != nil {
+		for j := range z.Offset {
+			fmt.Fprintf(w, "  input %d: (x - %.6g) * %.6g\n", j, z.Offset[j], z.Scale[j])
+		}
+	}
+	fmt.Fprintf(w, "Encoding:   %s\n", describeEncoding(n.Encoder))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-8s %-10s %10s %10s %10s %10s\n", "layer", "", "mean", "std", "min", "max")
+	for i, layer := range n.Layers {
+		for _, p := range []struct {
+			name   string
+			values []float64
+		}{{"weights", layer.Weights.Data}, {"biases", layer.Biases.Data}} {
+			mean, std, lo, hi := summarize(p.values)
+			fmt.Fprintf(w, "%-8d %-10s %10.4f %10.4f %10.4f %10.4f\n", i+1, p.name, mean, std, lo, hi)
+		}
+	}
+
+	fmt.Fprintln(w)
+	if n.Classes == nil {
+		fmt.Fprintln(w, "Classes:    not recorded")
+		return nil
+	}
+	fmt.Fprintf(w, "Classes:    %d\n", len(n.Classes))
+	for i, name := range n.Classes {
+		fmt.Fprintf(w, "  %3d %s\n", i, name)
+	}
+	return nil
+}
+
+// summarize returns the mean, population standard deviation, minimum and
+// maximum of values.
+func summarize(values []float64) (mean, std, lo, hi float64) {
+	if len(values) == 0 {
+		return 0, 0, 0, 0
+	}
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		mean += v
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	std = math.Sqrt(std / float64(len(values)))
+	return mean, std, lo, hi
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+var predictCommand = &command{
+	name:    "predict",
+	args:    "[input ...]",
+	summary: "classify inputs with a model",
+	help: `
+Predict classifies each input with the model at -model and prints the class
+name, its index and its output. An input is a list of numbers separated by
+commas or spaces, e.g. "77.2,28.6" for a longitude and latitude. Inputs are
+taken from the arguments, or read line by line from standard input when
+there are none. Models trained with -background name points outside every
+region "none".`,
+	flags: modelFlags,
+	run:   runPredict,
+}
+
+func runPredict(cfg Config, args []string) error {
+	n, err := network.Load(cfg.ModelPath)
+	if err != nil {
+		return err
+	}
+	predictor := n.NewPredictor()
+
+	predict := func(line string) error {
+		inputs, err := parseInputs(line)
+		if err != nil {
+			return err
+		}
+		class, outputs, err := predictor.Classify(inputs)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\t%d\t%.4f\n", className(n, class), class, outputs[class])
+		return nil
+	}
+
+	if len(args) > 0 {
+		for _, arg := range args {
+			if err := predict(arg); err != nil {
+				return usagef("%q: %v", arg, err)
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := predict(text); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseInputs(s string) ([]float64, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	inputs := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = v
+	}
+	return inputs, nil
+}
+
+func className(n *network.Network, class int) string {
+	if class >= 0 && class < len(n.Classes) {
+		return n.Classes[class]
+	}
+	return strconv.Itoa(class)
+}
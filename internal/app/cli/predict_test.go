@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseInputs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []float64
+		wantErr bool
+	}{
+		{"77.2,28.6", []float64{77.2, 28.6}, false},
+		{"77.2, 28.6", []float64{77.2, 28.6}, false},
+		{"77.2 28.6", []float64{77.2, 28.6}, false},
+		{"77.2\t28.6", []float64{77.2, 28.6}, false},
+		{" -1e3 ,, 0 ", []float64{-1000, 0}, false},
+		{"5", []float64{5}, false},
+		{"", []float64{}, false},
+		{"77.2;28.6", nil, true},
+		{"lon,lat", nil, true},
+		{"1,2,x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseInputs(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInputs(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("parseInputs(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: "serve predictions over HTTP",
+	help: `
+Serve loads the model at -model and answers prediction requests on -addr
+until interrupted.
+
+  POST /predict  {"inputs": [[77.2, 28.6], ...]}
+                 -> {"predictions": [{"class": 3, "label": "Delhi",
+                     "outputs": [...]}, ...]}
+  GET  /model    layer sizes and class names
+  GET  /healthz  200 once the model is loaded`,
+	flags: modelFlags | serveFlags,
+	run:   runServe,
+}
+
+// maxRequestBytes bounds the body of a prediction request.
+const maxRequestBytes = 16 << 20
+
+type predictRequest struct {
+	Inputs [][]float64 `json:"inputs"`
+}
+
+type prediction struct {
+	Class   int       `json:"class"`
+	Label   string    `json:"label,omitempty"`
+	Outputs []float64 `json:"outputs"`
+}
+
+type predictResponse struct {
+	Predictions []prediction `json:"predictions"`
+}
+
+type modelResponse struct {
+	Layers  []int    `json:"layers"`
+	Classes []string `json:"classes,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func runServe(cfg Config, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %v", args)
+	}
+	n, err := network.Load(cfg.ModelPath)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           newHandler(n),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.ListenAndServe()
+	}()
+	slog.Info("serving", "addr", cfg.Addr, "model", cfg.ModelPath)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newHandler routes the prediction API. Batches are predicted with
+// ClassifyBatch, which is safe to call from concurrent requests.
+func newHandler(n *network.Network) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /model", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, modelResponse{Layers: n.GetLayerSizes(), Classes: n.Classes})
+	})
+	mux.HandleFunc("POST /predict", func(w http.ResponseWriter, r *http.Request) {
+		var req predictRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		classes, outputs, err := n.ClassifyBatch(req.Inputs)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		resp := predictResponse{Predictions: make([]prediction, len(outputs))}
+		for i, out := range outputs {
+			class := classes[i]
+			resp.Predictions[i] = prediction{Class: class, Outputs: out}
+			if class < len(n.Classes) {
+				resp.Predictions[i].Label = n.Classes[class]
+			}
+		}
+		slog.Debug("predicted", "inputs", len(req.Inputs), "remote", r.RemoteAddr)
+		writeJSON(w, http.StatusOK, resp)
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("failed to write response", "err", err)
+	}
+}
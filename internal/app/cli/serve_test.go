@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+func testServer(t *testing.T) (*network.Network, *httptest.Server) {
+	t.Helper()
+	n := network.NewNetwork([]int{2, 4, 3})
+	n.Classes = []string{"a", "b", "c"}
+	server := httptest.NewServer(newHandler(n))
+	t.Cleanup(server.Close)
+	return n, server
+}
+
+func post(t *testing.T, server *httptest.Server, body string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/predict", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, raw
+}
+
+func TestServePredict(t *testing.T) {
+	n, server := testServer(t)
+	inputs := [][]float64{{77.2, 28.6}, {0, 0}, {-1, 5}}
+
+	body, _ := json.Marshal(predictRequest{Inputs: inputs})
+	status, raw := post(t, server, string(body))
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %s", status, raw)
+	}
+	var resp predictResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Predictions) != len(inputs) {
+		t.Fatalf("got %d predictions, want %d", len(resp.Predictions), len(inputs))
+	}
+	for i, in := range inputs {
+		want, err := n.Forward(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := resp.Predictions[i]
+		for j := range want {
+			if math.Abs(got.Outputs[j]-want[j]) > 1e-12 {
+				t.Fatalf("input %d: outputs = %v, want %v", i, got.Outputs, want)
+			}
+		}
+		class := slices.Index(want, slices.Max(want))
+		if got.Class != class || got.Label != n.Classes[class] {
+			t.Errorf("input %d: class %d %q, want %d %q", i, got.Class, got.Label, class, n.Classes[class])
+		}
+	}
+}
+
+func TestServePredictRejects(t *testing.T) {
+	_, server := testServer(t)
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"wrong input size", `{"inputs": [[1, 2], [1, 2, 3]]}`, "Input size"},
+		{"unknown field", `{"inputs": [[1, 2]], "model": "other"}`, `unknown field "model"`},
+		{"malformed json", `{"inputs": [[1, 2]`, "unexpected EOF"},
+		{"wrong type", `{"inputs": [1, 2]}`, "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, raw := post(t, server, tt.body)
+			if status != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+			var resp errorResponse
+			if err := json.Unmarshal(raw, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(resp.Error, tt.want) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeModel(t *testing.T) {
+	_, server := testServer(t)
+	resp, err := http.Get(server.URL + "/model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var model modelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&model); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(model.Layers, []int{2, 4, 3}) || !slices.Equal(model.Classes, []string{"a", "b", "c"}) {
+		t.Errorf("model = %+v", model)
+	}
+
+	// Only POST reaches the predictor.
+	resp, err = http.Get(server.URL + "/predict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /predict status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+var testCommand = &command{
+	name:    "test",
+	summary: "measure the accuracy of a model",
+	help: `
+Test reports the fraction of samples the model at -model classifies
+correctly. With GeoJSON regions it draws -test-count fresh points inside
+them; with -data or -test-data it evaluates every row of the dataset.
+
+A model trained with -background has a "none" class and is tested on
+points outside every region as well, -background of them or 10% when the
+flag is not given. Its accuracy on those points is reported separately.
+-background cannot be used with a model that has no "none" class.`,
+	flags: dataFlags | csvFlags | modelFlags | testFlags,
+	run:   runTest,
+}
+
+func runTest(cfg Config, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %v", args)
+	}
+
+	n, err := network.Load(cfg.ModelPath)
+	if err != nil {
+		return err
+	}
+
+	var correct, total int
+	var background, backgroundCorrect int
+	if path := testDataPath(cfg); path != "" {
+		data, err := loadTable(path, cfg, n.Classes)
+		if err != nil {
+			return err
+		}
+		predicted, err := n.PredictClasses(data.inputs)
+		if err != nil {
+			return err
+		}
+		for i := range predicted {
+			if predicted[i] == data.labels[i] {
+				correct++
+			}
+		}
+		total = len(data.labels)
+	} else {
+		// The model's classes decide whether background points are drawn;
+		// the flag only sets their fraction.
+		switch {
+		case hasBackground(n.Classes) && cfg.Background == 0:
+			cfg.Background = defaultTestBackground
+		case !hasBackground(n.Classes) && cfg.Background > 0:
+			return fmt.Errorf("model has no %q class, test it without -background", backgroundClass)
+		}
+		r, err := loadRegions(cfg)
+		if err != nil {
+			return err
+		}
+		if n.Classes != nil && !slices.Equal(n.Classes, r.classes) {
+			return fmt.Errorf("model classes do not match the labels of %s", cfg.GeojsonPath)
+		}
+
+		// Chunks of points are sampled concurrently and each chunk is
+		// classified with a single batched prediction.
+		t, err := routines.ParallelReduce(cfg.TestCount, 1024, tally{}, func(start, end int) tally {
+			data, err := r.sample(end - start)
+			if err != nil {
+				return tally{err: err}
+			}
+			predicted, err := n.PredictClasses(data.inputs)
+			if err != nil {
+				return tally{err: err}
+			}
+			var t tally
+			for i, label := range data.labels {
+				if label == r.background {
+					t.background++
+				}
+				if predicted[i] == label {
+					t.correct++
+					if label == r.background {
+						t.backgroundCorrect++
+					}
+				}
+			}
+			return t
+		}, func(a, b tally) tally {
+			return tally{
+				correct:           a.correct + b.correct,
+				background:        a.background + b.background,
+				backgroundCorrect: a.backgroundCorrect + b.backgroundCorrect,
+				err:               cmp.Or(a.err, b.err),
+			}
+		}, routines.WithSchedule(routines.Dynamic))
+		if err != nil {
+			return err
+		}
+		if t.err != nil {
+			return t.err
+		}
+		correct = t.correct
+		background, backgroundCorrect = t.background, t.backgroundCorrect
+		total = cfg.TestCount
+	}
+
+	if total == 0 {
+		return fmt.Errorf("no test samples")
+	}
+	fmt.Fprintf(stdout, "Accuracy: %.2f%% (%d/%d)\n", float64(correct)/float64(total)*100, correct, total)
+	if background > 0 {
+		fmt.Fprintf(stdout, "Accuracy on %s: %.2f%% (%d/%d)\n", backgroundClass, float64(backgroundCorrect)/float64(background)*100, backgroundCorrect, background)
+	}
+	return nil
+}
+
+// defaultTestBackground is the fraction of background points drawn to test
+// a model with a background class when -background is not given.
+const defaultTestBackground = 0.1
+
+// tally counts the correct predictions of a chunk of test points, overall
+// and among background points, or holds the error that stopped it.
+type tally struct {
+	correct                       int
+	background, backgroundCorrect int
+	err                           error
+}
+
+// hasBackground reports whether a model's classes end with the background
+// class. Training rejects regions of that name, so it is never a region.
+func hasBackground(classes []string) bool {
+	return len(classes) > 0 && classes[len(classes)-1] == backgroundClass
+}
+
+func testDataPath(cfg Config) string {
+	if cfg.TestData != "" {
+		return cfg.TestData
+	}
+	return cfg.Data
+}
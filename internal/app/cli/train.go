@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+var trainCommand = &command{
+	name:    "train",
+	summary: "train a model on GeoJSON regions or a dataset",
+	help: `
+Train samples points inside the GeoJSON regions, or reads -data, and trains
+the model at -model on them. An existing model is trained further when its
+layer sizes, classes, normalization and encoding still match; otherwise a
+new one is created. The model directory is created if needed.
+
+Points are drawn with the -sampling strategy:
+
+  uniform     over the bounding box, so small regions get few samples
+  stratified  an equal share for every class
+  area        a share in proportion to every region's area, drawn within
+              the region's own polygons
+  boundary    -boundary-fraction of the points within about -boundary-width
+              degrees of a polygon edge, the rest uniformly
+
+Sampling fails when a point takes more than -max-attempts candidates.
+With -background, that fraction of the points is drawn outside every region
+but within -margin degrees of the map and labelled with an extra "none"
+class.
+
+A new model fits the -normalize transform to its training inputs and saves
+it, so test, predict and serve scale inputs the same way:
+
+  none      raw inputs
+  minmax    every input's range in the training data onto [-1, 1]
+  standard  every input to zero mean and unit variance
+  bounds    longitude and latitude of the GeoJSON bounding box onto [-1, 1]
+
+and expands the normalized inputs with the -encoding features, also saved:
+
+  none      the normalized inputs only
+  sincos    sin and cos of every input at -frequencies octaves
+  fourier   sin and cos of -frequencies random projections of the inputs,
+            drawn with standard deviation -fourier-scale
+
+-sphere adds the position of the raw longitude and latitude on the unit
+sphere to the features. Encoded inputs usually need a smaller -lr.`,
+	flags: dataFlags | csvFlags | modelFlags | trainFlags,
+	run:   runTrain,
+}
+
+func runTrain(cfg Config, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments: %v", args)
+	}
+
+	if err := prepareModelPath(cfg.ModelPath); err != nil {
+		return err
+	}
+	data, err := loadTrainingSet(cfg)
+	if err != nil {
+		return err
+	}
+	if len(data.inputs) == 0 {
+		return fmt.Errorf("no training samples")
+	}
+
+	n, err := loadOrCreate(data, cfg)
+	if err != nil {
+		return err
+	}
+	n.Classes = data.classes
+
+	slog.Info("training", "trainer", cfg.Trainer, "samples", len(data.inputs), "epochs", cfg.Epochs, "layers", n.GetLayerSizes())
+	start := time.Now()
+	if err := train(n, data, cfg); err != nil {
+		return err
+	}
+	slog.Info("training complete", "took", time.Since(start).Round(time.Millisecond))
+
+	if err := n.Save(cfg.ModelPath); err != nil {
+		return err
+	}
+	slog.Info("model saved", "path", cfg.ModelPath)
+	return nil
+}
+
+// prepareModelPath creates the directory of the model and checks that a file
+// can be written there, so that a bad -model fails before training instead
+// of losing the trained network afterwards.
+func prepareModelPath(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("model path %s is a directory", path)
+	}
+	probe, err := os.CreateTemp(dir, ".nn-write-check-*")
+	if err != nil {
+		return fmt.Errorf("model directory is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// loadOrCreate loads the model at cfg.ModelPath to continue training it, or
+// creates a new network if there is none or it no longer fits the data and
+// the configured input transforms. A loaded model keeps its fitted
+// normalizer and encoder.
+func loadOrCreate(data *dataset, cfg Config) (*network.Network, error) {
+	fresh, err := newNetwork(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	path := cfg.ModelPath
+	n, err := network.Load(path)
+	switch {
+	case err != nil:
+		slog.Info("creating a new network", "reason", err)
+	case !slices.Equal(n.GetLayerSizes(), fresh.GetLayerSizes()):
+		slog.Info("model architecture has changed, creating a new network", "path", path)
+	case n.Classes != nil && !slices.Equal(n.Classes, data.classes):
+		slog.Info("model classes have changed, creating a new network", "path", path)
+	case normalizerKind(n.Normalizer) != normalizerKind(fresh.Normalizer):
+		slog.Info("model normalization has changed, creating a new network", "path", path)
+	case !sameEncoding(n.Encoder, fresh.Encoder):
+		slog.Info("model encoding has changed, creating a new network", "path", path)
+	default:
+		slog.Info("loaded existing model, continuing training", "path", path)
+		return n, nil
+	}
+	return fresh, nil
+}
+
+// newNetwork creates a network for data with the configured hidden layers,
+// encoder and a normalizer fitted to data.
+func newNetwork(data *dataset, cfg Config) (*network.Network, error) {
+	inputs := len(data.inputs[0])
+	e, err := newEncoder(cfg, inputs)
+	if err != nil {
+		return nil, err
+	}
+	layerSizes := []int{inputs}
+	if e != nil {
+		layerSizes[0] = e.Size()
+	}
+	layerSizes = append(layerSizes, cfg.HiddenLayerSizes...)
+	layerSizes = append(layerSizes, len(data.classes))
+
+	n := network.NewNetwork(layerSizes)
+	if err := n.SetEncoder(e); err != nil {
+		return nil, err
+	}
+	z, err := fitNormalizer(cfg.Normalize, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.SetNormalizer(z); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// train runs cfg.Epochs epochs with the configured trainer.
+func train(n *network.Network, data *dataset, cfg Config) error {
+	switch cfg.Trainer {
+	case "data-parallel":
+		return network.NewDataParallelTrainer(n, cfg.Workers).TrainLoopClasses(data.inputs, data.labels, cfg.LearningRate, cfg.Epochs, cfg.BatchSize)
+	case "hogwild":
+		return network.NewHogwildTrainer(n, cfg.Workers).TrainLoopClasses(data.inputs, data.labels, cfg.LearningRate, cfg.Epochs)
+	default:
+		return n.TrainLoopClasses(data.inputs, data.labels, cfg.LearningRate, cfg.Epochs)
+	}
+}
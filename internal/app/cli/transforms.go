@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/whyisemerald/neural_network/internals/network"
+)
+
+// normalizeNone disables input normalization.
+const normalizeNone = "none"
+
+func fitNormalizer(kind string, data *dataset) (*network.Normalizer, error) {
+	switch kind {
+	case network.NormalizeMinMax:
+		return network.NewMinMaxNormalizer(data.inputs)
+	case network.NormalizeStandard:
+		return network.NewStandardNormalizer(data.inputs)
+	case network.NormalizeBounds:
+		if data.lower == nil {
+			return nil, fmt.Errorf("bounds normalization needs GeoJSON regions, use minmax or standard with -data")
+		}
+		return network.NewBoundsNormalizer(data.lower, data.upper)
+	default:
+		return nil, nil
+	}
+}
+
+func normalizerKind(z *network.Normalizer) string {
+	if z == nil {
+		return normalizeNone
+	}
+	return z.Kind
+}
+
+// encodeNone disables feature encoding.
+const encodeNone = "none"
+
+// newEncoder builds the configured encoder for the given number of inputs,
+// or returns nil when neither an encoding nor sphere coordinates are set.
+func newEncoder(cfg Config, inputs int) (*network.Encoder, error) {
+	var e *network.Encoder
+	var err error
+	switch cfg.Encoding {
+	case network.EncodeSinCos:
+		e, err = network.NewSinCosEncoder(inputs, cfg.Frequencies)
+	case network.EncodeFourier:
+		e, err = network.NewFourierEncoder(inputs, cfg.Frequencies, cfg.FourierScale, rand.New(rand.NewSource(rand.Int63())))
+	default:
+		if !cfg.Sphere {
+			return nil, nil
+		}
+		e = &network.Encoder{Inputs: inputs}
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.Sphere = cfg.Sphere
+	return e, nil
+}
+
+// sameEncoding reports whether a and b were built from the same settings.
+func sameEncoding(a, b *network.Encoder) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Kind == b.Kind && a.Inputs == b.Inputs && a.Frequencies == b.Frequencies && a.Scale == b.Scale && a.Sphere == b.Sphere
+}
+
+// describeEncoding names the features e produces.
+func describeEncoding(e *network.Encoder) string {
+	if e == nil {
+		return encodeNone
+	}
+	var s string
+	switch e.Kind {
+	case network.EncodeSinCos:
+		s = fmt.Sprintf("sincos, %d octaves", e.Frequencies)
+	case network.EncodeFourier:
+		s = fmt.Sprintf("fourier, %d frequencies, scale %g", e.Frequencies, e.Scale)
+	default:
+		s = encodeNone
+	}
+	if e.Sphere {
+		s += ", sphere"
+	}
+	return fmt.Sprintf("%s (%d inputs -> %d features)", s, e.Inputs, e.Size())
+}
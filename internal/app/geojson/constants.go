@@ -1,26 +0,0 @@
-package geojson
-
-const (
-	// GeojsonPath is the path to the GeoJSON file
-	GeojsonPath = "data/INDIA/india.geojson"
-
-	// ModelPath is the path to save the trained model
-	ModelPath = "examples/geojson/saves/model.json"
-
-	// NumSamples is the number of points to generate for training
-	NumSamples = 500000
-
-	// LearningRate is the learning rate for training
-	LearningRate = 0.1
-
-	// Epochs is the number of training cycles
-	Epochs = 500
-
-	// TestCount is the number of test points to generate for accuracy testing
-	TestCount = 1000000
-)
-
-var (
-	// HiddenLayerSizes is a slice of integers representing the size of each hidden layer
-	HiddenLayerSizes = []int{20, 35, 20}
-)
@@ -1,36 +1,427 @@
 package geojson
 
-import "math/rand"
-
-func GenerateTrainingData(geoData *ExtractedGeoJSON, numSamples int) ([][]float64, [][]float64) {
-	inputs := make([][]float64, numSamples)
-	expected := make([][]float64, numSamples)
-	numClasses := len(geoData.FeatureCollection.Features)
-
-	for i := 0; i < numSamples; i++ {
-		var point Point
-		var regionIndex int
-		for {
-			lon := geoData.MinLon + rand.Float64()*(geoData.MaxLon-geoData.MinLon)
-			lat := geoData.MinLat + rand.Float64()*(geoData.MaxLat-geoData.MinLat)
-			point = Point{lon, lat}
-
-			found := false
-			for j, mp := range geoData.MultiPolygons {
-				if IsPointInMultiPolygon(point, mp) {
-					regionIndex = j
-					found = true
-					break
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Sampling strategies.
+const (
+	// SampleUniform draws points uniformly over the bounding box of all
+	// regions, so every region gets samples in proportion to its area and
+	// small regions get almost none.
+	SampleUniform = "uniform"
+	// SampleStratified gives every stratum, by default every region, an
+	// equal share of the samples.
+	SampleStratified = "stratified"
+	// SampleArea gives every region an exact share of the samples in
+	// proportion to its area, drawing each one within the region's own
+	// polygons rather than the whole bounding box.
+	SampleArea = "area"
+	// SampleBoundary draws BoundaryFraction of the samples near polygon
+	// edges and the rest uniformly.
+	SampleBoundary = "boundary"
+)
+
+// SampleOptions configures GenerateTrainingData and NewSampler.
+type SampleOptions struct {
+	Strategy string
+	// Strata assigns every region to a stratum for SampleStratified, e.g.
+	// its class when several regions share one. Nil puts every region in a
+	// stratum of its own.
+	Strata []int
+	// BoundaryFraction is the fraction of SampleBoundary samples drawn near
+	// edges.
+	BoundaryFraction float64
+	// BoundaryWidth is the standard deviation, in degrees, of the distance
+	// of boundary samples from the edge they were drawn on.
+	BoundaryWidth float64
+	// MaxAttempts bounds the candidate points drawn for one sample before
+	// sampling fails, e.g. because a region is too thin to hit.
+	MaxAttempts int
+	// Background is the fraction of samples drawn outside every region and
+	// labelled BackgroundRegion. Zero disables background samples.
+	Background float64
+	// Margin is how far, in degrees, background samples may lie outside
+	// the bounding box of the regions.
+	Margin float64
+}
+
+var DefaultSampleOptions = SampleOptions{
+	Strategy:         SampleUniform,
+	BoundaryFraction: 0.5,
+	BoundaryWidth:    0.05,
+	MaxAttempts:      10000,
+	Margin:           1,
+}
+
+// BackgroundRegion is the label of samples outside every region.
+const BackgroundRegion = -1
+
+// Sampler draws labelled points from the regions of an ExtractedGeoJSON.
+// The per-region tables it needs are built once, and Sample is safe for
+// concurrent use with a rand.Rand per goroutine.
+type Sampler struct {
+	data   *ExtractedGeoJSON
+	opts   SampleOptions
+	bounds BBox
+
+	// strata lists the polygons of every stratum for the quota strategies,
+	// weights the share of the samples each stratum gets.
+	strata       []stratum
+	regionStrata []int
+	weights      []float64
+
+	// edges and edgeLengths are every polygon edge and the running total of
+	// their lengths, for SampleBoundary.
+	edges       [][4]float64
+	edgeLengths []float64
+}
+
+type stratum struct {
+	boxes []BBox
+	// areas is the running total of the areas of boxes' polygons.
+	areas []float64
+}
+
+// NewSampler validates opts and prepares to sample geoData.
+func NewSampler(geoData *ExtractedGeoJSON, opts SampleOptions) (*Sampler, error) {
+	var errs []error
+	switch opts.Strategy {
+	case SampleUniform, SampleStratified, SampleArea, SampleBoundary:
+	default:
+		errs = append(errs, fmt.Errorf("unknown sampling strategy %q", opts.Strategy))
+	}
+	if opts.Strata != nil && len(opts.Strata) != len(geoData.MultiPolygons) {
+		errs = append(errs, fmt.Errorf("got strata for %d regions, want %d", len(opts.Strata), len(geoData.MultiPolygons)))
+	}
+	if !(opts.BoundaryFraction >= 0 && opts.BoundaryFraction <= 1) {
+		errs = append(errs, fmt.Errorf("boundary fraction must be within [0, 1], got %v", opts.BoundaryFraction))
+	}
+	if !(opts.BoundaryWidth > 0) || math.IsInf(opts.BoundaryWidth, 0) {
+		errs = append(errs, fmt.Errorf("boundary width must be a positive number, got %v", opts.BoundaryWidth))
+	}
+	if opts.MaxAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("max attempts must be positive, got %d", opts.MaxAttempts))
+	}
+	if !(opts.Background >= 0 && opts.Background <= 1) {
+		errs = append(errs, fmt.Errorf("background fraction must be within [0, 1], got %v", opts.Background))
+	}
+	if !(opts.Margin >= 0) || math.IsInf(opts.Margin, 0) {
+		errs = append(errs, fmt.Errorf("margin must be a non-negative number, got %v", opts.Margin))
+	}
+	if len(geoData.MultiPolygons) == 0 {
+		errs = append(errs, errors.New("no regions to sample"))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	s := &Sampler{
+		data:   geoData,
+		opts:   opts,
+		bounds: BBox{MinLon: geoData.MinLon, MinLat: geoData.MinLat, MaxLon: geoData.MaxLon, MaxLat: geoData.MaxLat},
+	}
+	switch opts.Strategy {
+	case SampleStratified, SampleArea:
+		if err := s.buildStrata(); err != nil {
+			return nil, err
+		}
+	case SampleBoundary:
+		s.buildEdges()
+	}
+	return s, nil
+}
+
+func (s *Sampler) buildStrata() error {
+	s.regionStrata = s.opts.Strata
+	if s.opts.Strategy == SampleArea || s.regionStrata == nil {
+		s.regionStrata = make([]int, len(s.data.MultiPolygons))
+		for i := range s.regionStrata {
+			s.regionStrata[i] = i
+		}
+	}
+	numStrata := 0
+	for _, st := range s.regionStrata {
+		if st < 0 {
+			return fmt.Errorf("stratum %d is negative", st)
+		}
+		numStrata = max(numStrata, st+1)
+	}
+
+	s.strata = make([]stratum, numStrata)
+	s.weights = make([]float64, numStrata)
+	for region, mp := range s.data.MultiPolygons {
+		st := &s.strata[s.regionStrata[region]]
+		for _, polygon := range mp {
+			pp := preparePolygon(polygon)
+			if pp.empty {
+				continue
+			}
+			total := polygonArea(pp.rings)
+			if len(st.areas) > 0 {
+				total += st.areas[len(st.areas)-1]
+			}
+			st.boxes = append(st.boxes, pp.bbox)
+			st.areas = append(st.areas, total)
+		}
+	}
+
+	var errs []error
+	for i, st := range s.strata {
+		if len(st.boxes) == 0 {
+			errs = append(errs, fmt.Errorf("stratum %d has no polygons to sample", i))
+			continue
+		}
+		s.weights[i] = 1
+		if s.opts.Strategy == SampleArea {
+			s.weights[i] = st.areas[len(st.areas)-1]
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sampler) buildEdges() {
+	total := 0.0
+	for _, mp := range s.data.MultiPolygons {
+		for _, polygon := range mp {
+			pp := preparePolygon(polygon)
+			for _, ring := range pp.rings {
+				for i := 1; i < len(ring); i++ {
+					a, b := ring[i-1], ring[i]
+					length := math.Hypot(b[0]-a[0], b[1]-a[1])
+					if length == 0 {
+						continue
+					}
+					total += length
+					s.edges = append(s.edges, [4]float64{a[0], a[1], b[0], b[1]})
+					s.edgeLengths = append(s.edgeLengths, total)
+				}
+			}
+		}
+	}
+}
+
+// Bounds is the extent samples are drawn from: the bounding box of the
+// regions, grown by Margin when there are background samples.
+func (s *Sampler) Bounds() BBox {
+	if s.opts.Background > 0 {
+		return s.bounds.grow(s.opts.Margin)
+	}
+	return s.bounds
+}
+
+// Sample draws n points and returns them with the index of the region each
+// one falls in, or BackgroundRegion. It fails if a sample needs more than
+// MaxAttempts candidate points.
+func (s *Sampler) Sample(n int, rng *rand.Rand) ([][]float64, []int, error) {
+	inputs := make([][]float64, 0, n)
+	labels := make([]int, 0, n)
+	add := func(p Point, region int) {
+		inputs = append(inputs, []float64{p[0], p[1]})
+		labels = append(labels, region)
+	}
+
+	background := int(math.Round(float64(n) * s.opts.Background))
+	for range background {
+		p, err := s.sampleBackground(rng)
+		if err != nil {
+			return nil, nil, err
+		}
+		add(p, BackgroundRegion)
+	}
+	n -= background
+
+	switch s.opts.Strategy {
+	case SampleStratified, SampleArea:
+		for st, count := range quotas(n, s.weights, rng) {
+			for range count {
+				p, region, err := s.sampleStratum(st, rng)
+				if err != nil {
+					return nil, nil, err
 				}
+				add(p, region)
 			}
-			if found {
-				break
+		}
+	case SampleBoundary:
+		near := 0
+		if len(s.edges) > 0 {
+			near = int(math.Round(float64(n) * s.opts.BoundaryFraction))
+		}
+		for i := 0; i < n; i++ {
+			sample := s.sampleUniform
+			if i < near {
+				sample = s.sampleBoundary
 			}
+			p, region, err := sample(rng)
+			if err != nil {
+				return nil, nil, err
+			}
+			add(p, region)
+		}
+	default:
+		for i := 0; i < n; i++ {
+			p, region, err := s.sampleUniform(rng)
+			if err != nil {
+				return nil, nil, err
+			}
+			add(p, region)
+		}
+	}
+
+	// The strategies produce samples grouped by stratum or kind; shuffle
+	// them so that consecutive training samples are independent.
+	rng.Shuffle(len(inputs), func(i, j int) {
+		inputs[i], inputs[j] = inputs[j], inputs[i]
+		labels[i], labels[j] = labels[j], labels[i]
+	})
+	return inputs, labels, nil
+}
+
+func (s *Sampler) sampleUniform(rng *rand.Rand) (Point, int, error) {
+	for range s.opts.MaxAttempts {
+		p := Point{
+			s.bounds.MinLon + rng.Float64()*(s.bounds.MaxLon-s.bounds.MinLon),
+			s.bounds.MinLat + rng.Float64()*(s.bounds.MaxLat-s.bounds.MinLat),
+		}
+		if region, ok := s.data.Locate(p); ok {
+			return p, region, nil
+		}
+	}
+	return nil, -1, fmt.Errorf("no point inside any region after %d attempts", s.opts.MaxAttempts)
+}
+
+// sampleBackground draws points within Margin of the bounding box until one
+// falls outside every region.
+func (s *Sampler) sampleBackground(rng *rand.Rand) (Point, error) {
+	b := s.bounds.grow(s.opts.Margin)
+	for range s.opts.MaxAttempts {
+		p := Point{
+			wrapLon(b.MinLon + rng.Float64()*(b.MaxLon-b.MinLon)),
+			b.MinLat + rng.Float64()*(b.MaxLat-b.MinLat),
+		}
+		if _, ok := s.data.Locate(p); !ok {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no point outside every region after %d attempts, try a larger margin", s.opts.MaxAttempts)
+}
+
+// sampleStratum picks a polygon of the stratum by area and draws points in
+// its bounding box until one falls in a region of the stratum.
+func (s *Sampler) sampleStratum(i int, rng *rand.Rand) (Point, int, error) {
+	st := &s.strata[i]
+	for range s.opts.MaxAttempts {
+		box := st.boxes[pickWeighted(st.areas, rng)]
+		p := Point{
+			wrapLon(box.MinLon + rng.Float64()*(box.MaxLon-box.MinLon)),
+			box.MinLat + rng.Float64()*(box.MaxLat-box.MinLat),
+		}
+		if region, ok := s.data.Locate(p); ok && s.regionStrata[region] == i {
+			return p, region, nil
 		}
+	}
+	return nil, -1, fmt.Errorf("no point inside stratum %d after %d attempts", i, s.opts.MaxAttempts)
+}
+
+// sampleBoundary picks an edge by length and a point on it, and moves the
+// point off the edge by a normally distributed distance.
+func (s *Sampler) sampleBoundary(rng *rand.Rand) (Point, int, error) {
+	for range s.opts.MaxAttempts {
+		e := s.edges[pickWeighted(s.edgeLengths, rng)]
+		t := rng.Float64()
+		p := Point{
+			wrapLon(e[0] + t*(e[2]-e[0]) + rng.NormFloat64()*s.opts.BoundaryWidth),
+			e[1] + t*(e[3]-e[1]) + rng.NormFloat64()*s.opts.BoundaryWidth,
+		}
+		if region, ok := s.data.Locate(p); ok {
+			return p, region, nil
+		}
+	}
+	return nil, -1, fmt.Errorf("no point near a boundary inside a region after %d attempts", s.opts.MaxAttempts)
+}
+
+// GenerateTrainingData samples points inside the regions and returns them
+// with the index of the region each one falls in. Callers sampling
+// repeatedly should build a Sampler once instead.
+func GenerateTrainingData(geoData *ExtractedGeoJSON, numSamples int, opts SampleOptions) ([][]float64, []int, error) {
+	s, err := NewSampler(geoData, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.Sample(numSamples, rand.New(rand.NewSource(rand.Int63())))
+}
+
+// quotas splits n samples in proportion to weights. The remainder left by
+// rounding down is handed out at random by weight.
+func quotas(n int, weights []float64, rng *rand.Rand) []int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	counts := make([]int, len(weights))
+	if total <= 0 {
+		return counts
+	}
+	cumulative := make([]float64, len(weights))
+	assigned, sum := 0, 0.0
+	for i, w := range weights {
+		counts[i] = int(float64(n) * w / total)
+		assigned += counts[i]
+		sum += w
+		cumulative[i] = sum
+	}
+	for ; assigned < n; assigned++ {
+		counts[pickWeighted(cumulative, rng)]++
+	}
+	return counts
+}
+
+// pickWeighted returns an index drawn with probability proportional to the
+// increments of cumulative. If every weight is zero it picks uniformly.
+func pickWeighted(cumulative []float64, rng *rand.Rand) int {
+	total := cumulative[len(cumulative)-1]
+	if !(total > 0) {
+		return rng.Intn(len(cumulative))
+	}
+	x := rng.Float64() * total
+	i := sort.Search(len(cumulative), func(i int) bool {
+		return cumulative[i] > x
+	})
+	return min(i, len(cumulative)-1)
+}
+
+// polygonArea is the planar area in square degrees of an outer ring minus
+// its holes.
+func polygonArea(rings [][][]float64) float64 {
+	area := 0.0
+	for i, ring := range rings {
+		a := math.Abs(ringArea(ring))
+		if i == 0 {
+			area += a
+		} else {
+			area -= a
+		}
+	}
+	return max(area, 0)
+}
+
+// ringArea is the signed shoelace area of a closed ring.
+func ringArea(ring [][]float64) float64 {
+	sum := 0.0
+	for i := 1; i < len(ring); i++ {
+		sum += ring[i-1][0]*ring[i][1] - ring[i][0]*ring[i-1][1]
+	}
+	return sum / 2
+}
 
-		inputs[i] = []float64{point[0], point[1]}
-		expected[i] = make([]float64, numClasses)
-		expected[i][regionIndex] = 1
+// wrapLon moves an unwrapped longitude back into [-180, 180].
+func wrapLon(lon float64) float64 {
+	if lon > 180 || lon < -180 {
+		lon -= 360 * math.Round(lon/360)
 	}
-	return inputs, expected
+	return lon
 }
@@ -0,0 +1,236 @@
+package geojson
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// rectangle is the coordinates of a Polygon covering the given box.
+func rectangle(minLon, minLat, maxLon, maxLat float64) string {
+	return fmt.Sprintf("[[[%[1]v,%[2]v],[%[3]v,%[2]v],[%[3]v,%[4]v],[%[1]v,%[4]v],[%[1]v,%[2]v]]]",
+		minLon, minLat, maxLon, maxLat)
+}
+
+// sliver is a triangle along the diagonal of a 10 degree box that is far
+// too thin for random points to hit.
+const sliver = `[[[0,0],[10,10],[10,10.000000001],[0,0]]]`
+
+// extractPolygons parses one Polygon feature per coordinates string.
+func extractPolygons(t *testing.T, polygons ...string) *ExtractedGeoJSON {
+	t.Helper()
+	features := make([]string, len(polygons))
+	for i, coordinates := range polygons {
+		features[i] = feature(`{"type":"Polygon","coordinates":` + coordinates + `}`)
+	}
+	e, err := ExtractGeoJSON([]byte(collection(features...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func sampleOptions(strategy string) SampleOptions {
+	opts := DefaultSampleOptions
+	opts.Strategy = strategy
+	return opts
+}
+
+// sampleCounts samples n points and counts them per region, checking that
+// every label is the region its point lies in. Background samples are
+// counted last.
+func sampleCounts(t *testing.T, e *ExtractedGeoJSON, opts SampleOptions, n int) ([][]float64, []int) {
+	t.Helper()
+	s, err := NewSampler(e, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, labels, err := s.Sample(n, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != n || len(labels) != n {
+		t.Fatalf("got %d inputs and %d labels, want %d", len(inputs), len(labels), n)
+	}
+	counts := make([]int, len(e.MultiPolygons)+1)
+	for i, label := range labels {
+		region, ok := e.Locate(Point(inputs[i]))
+		if !ok {
+			region = BackgroundRegion
+		}
+		if region != label {
+			t.Fatalf("sample %v is labelled %d but lies in %d", inputs[i], label, region)
+		}
+		if label == BackgroundRegion {
+			label = len(e.MultiPolygons)
+		}
+		counts[label]++
+	}
+	return inputs, counts
+}
+
+func TestSampleStratified(t *testing.T) {
+	// The regions differ in area by a factor of 100, which stratified
+	// sampling must ignore.
+	e := extractPolygons(t, rectangle(0, 0, 10, 10), rectangle(20, 0, 21, 1), rectangle(30, 0, 32, 2))
+
+	_, counts := sampleCounts(t, e, sampleOptions(SampleStratified), 300)
+	if want := []int{100, 100, 100, 0}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	// The remainder of 302 / 3 goes to two different strata.
+	_, counts = sampleCounts(t, e, sampleOptions(SampleStratified), 302)
+	for region, count := range counts[:3] {
+		if count != 100 && count != 101 {
+			t.Errorf("region %d got %d samples, want 100 or 101", region, count)
+		}
+	}
+
+	opts := sampleOptions(SampleStratified)
+	opts.Strata = []int{0, 0, 1}
+	_, counts = sampleCounts(t, e, opts, 300)
+	if counts[0]+counts[1] != 150 || counts[2] != 150 {
+		t.Errorf("counts = %v, want 150 in stratum 0 and 150 in stratum 1", counts)
+	}
+}
+
+func TestSampleArea(t *testing.T) {
+	// Areas 1, 2 and 1.
+	e := extractPolygons(t, rectangle(0, 0, 1, 1), rectangle(2, 0, 4, 1), rectangle(5, 0, 6, 1))
+
+	_, counts := sampleCounts(t, e, sampleOptions(SampleArea), 400)
+	if want := []int{100, 200, 100, 0}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+
+	// SampleArea ignores strata: every region gets its own share.
+	opts := sampleOptions(SampleArea)
+	opts.Strata = []int{0, 0, 0}
+	_, counts = sampleCounts(t, e, opts, 400)
+	if want := []int{100, 200, 100, 0}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts with strata = %v, want %v", counts, want)
+	}
+
+	// A region without area gets no samples rather than exhausting its
+	// attempts.
+	e = extractPolygons(t, rectangle(20, 0, 30, 10), sliver)
+	_, counts = sampleCounts(t, e, sampleOptions(SampleArea), 100)
+	if want := []int{100, 0, 0}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts with a sliver = %v, want %v", counts, want)
+	}
+}
+
+func TestSampleBoundary(t *testing.T) {
+	// Perimeters 4 and 12, so about a quarter of the edge samples belong to
+	// the small region.
+	e := extractPolygons(t, rectangle(0, 0, 1, 1), rectangle(5, 0, 8, 3))
+	opts := sampleOptions(SampleBoundary)
+	opts.BoundaryFraction = 1
+	opts.BoundaryWidth = 0.01
+
+	inputs, counts := sampleCounts(t, e, opts, 2000)
+	if counts[0] < 400 || counts[0] > 600 || counts[0]+counts[1] != 2000 {
+		t.Errorf("counts = %v, want about 500 and 1500", counts)
+	}
+	for _, p := range inputs {
+		if d := edgeDistance(e, p); d > 10*opts.BoundaryWidth {
+			t.Fatalf("sample %v is %v from the nearest edge", p, d)
+		}
+	}
+
+	// Without boundary samples the strategy is uniform over the bounding
+	// box, where the small region has 1/10 of the area.
+	opts.BoundaryFraction = 0
+	_, counts = sampleCounts(t, e, opts, 2000)
+	if counts[0] < 120 || counts[0] > 280 {
+		t.Errorf("uniform counts = %v, want about 200 and 1800", counts)
+	}
+}
+
+func TestSampleBackground(t *testing.T) {
+	e := extractPolygons(t, rectangle(0, 0, 1, 1), rectangle(5, 0, 8, 3))
+	opts := sampleOptions(SampleStratified)
+	opts.Background = 0.25
+	opts.Margin = 2
+
+	s, err := NewSampler(e, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BBox{MinLon: -2, MinLat: -2, MaxLon: 10, MaxLat: 5}); s.Bounds() != want {
+		t.Errorf("Bounds() = %+v, want %+v", s.Bounds(), want)
+	}
+	inputs, counts := sampleCounts(t, e, opts, 400)
+	if want := []int{150, 150, 100}; fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	for _, p := range inputs {
+		if !s.Bounds().Contains(p[0], p[1]) {
+			t.Fatalf("sample %v lies outside %+v", p, s.Bounds())
+		}
+	}
+
+	// Without a margin, a region filling its bounding box leaves no room
+	// for background points.
+	opts.Margin = 0
+	opts.MaxAttempts = 1000
+	s, err = NewSampler(extractPolygons(t, rectangle(0, 0, 1, 1)), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = s.Sample(100, rand.New(rand.NewSource(1)))
+	if want := "no point outside every region after 1000 attempts"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Sample() error = %v, want %q", err, want)
+	}
+}
+
+// edgeDistance is the distance from p to the nearest polygon edge in e.
+func edgeDistance(e *ExtractedGeoJSON, p []float64) float64 {
+	best := math.Inf(1)
+	for _, mp := range e.MultiPolygons {
+		for _, polygon := range mp {
+			for _, ring := range polygon {
+				for i := 1; i < len(ring); i++ {
+					a, b := ring[i-1], ring[i]
+					dx, dy := b[0]-a[0], b[1]-a[1]
+					t := ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / (dx*dx + dy*dy)
+					t = min(max(t, 0), 1)
+					best = min(best, math.Hypot(p[0]-a[0]-t*dx, p[1]-a[1]-t*dy))
+				}
+			}
+		}
+	}
+	return best
+}
+
+func TestSampleAttemptCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		polygons []string
+		want     string
+	}{
+		{"uniform", SampleUniform, []string{sliver}, "no point inside any region after 1000 attempts"},
+		{"stratified", SampleStratified, []string{rectangle(20, 0, 30, 10), sliver}, "no point inside stratum 1 after 1000 attempts"},
+		{"boundary", SampleBoundary, []string{sliver}, "no point near a boundary inside a region after 1000 attempts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := sampleOptions(tt.strategy)
+			opts.MaxAttempts = 1000
+			s, err := NewSampler(extractPolygons(t, tt.polygons...), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Enough samples that the thin region is certain to get some.
+			_, _, err = s.Sample(100, rand.New(rand.NewSource(1)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Sample() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package geojson
+
+import "math"
+
+// BBox is an axis-aligned box in degrees.
+type BBox struct {
+	MinLon, MinLat float64
+	MaxLon, MaxLat float64
+}
+
+func (b BBox) Contains(lon, lat float64) bool {
+	return lon >= b.MinLon && lon <= b.MaxLon && lat >= b.MinLat && lat <= b.MaxLat
+}
+
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon && b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat
+}
+
+func (b BBox) extend(lon, lat float64) BBox {
+	b.MinLon = min(b.MinLon, lon)
+	b.MaxLon = max(b.MaxLon, lon)
+	b.MinLat = min(b.MinLat, lat)
+	b.MaxLat = max(b.MaxLat, lat)
+	return b
+}
+
+func (b BBox) union(o BBox) BBox {
+	return b.extend(o.MinLon, o.MinLat).extend(o.MaxLon, o.MaxLat)
+}
+
+func (b BBox) grow(d float64) BBox {
+	return BBox{MinLon: b.MinLon - d, MinLat: b.MinLat - d, MaxLon: b.MaxLon + d, MaxLat: b.MaxLat + d}
+}
+
+func (b BBox) shift(lon float64) BBox {
+	b.MinLon += lon
+	b.MaxLon += lon
+	return b
+}
+
+const (
+	maxGridSide = 512
+	maxRingBand = 4096
+	// edgesPerBand is the average number of edges a ring is split into per
+	// latitude band.
+	edgesPerBand = 4
+)
+
+// SpatialIndex answers which region a point falls in without testing every
+// edge of every polygon. A uniform grid over the regions' extent lists the
+// polygons whose bounding box touches each cell, and every ring keeps its
+// edges bucketed into latitude bands, so a point is only tested against the
+// few edges that can cross a horizontal line through it.
+//
+// The index is read-only once built and safe for concurrent use.
+type SpatialIndex struct {
+	opts     PIPOptions
+	polygons []indexedPolygon
+	bounds   BBox
+
+	cols, rows   int
+	cellW, cellH float64
+	cells        [][]int32
+}
+
+type indexedPolygon struct {
+	preparedPolygon
+	region int
+	rings  []ringIndex
+}
+
+// ringIndex is a ring with its edges in latitude bands, stored CSR style:
+// the edges of band b are edges[bandPtr[b]:bandPtr[b+1]], where edge i runs
+// from the previous vertex to vertex i.
+type ringIndex struct {
+	ring    [][]float64
+	minLat  float64
+	maxLat  float64
+	bandH   float64
+	bandPtr []int32
+	edges   []int32
+}
+
+// NewSpatialIndex indexes the polygons of every region. Locate returns the
+// position of the region in multiPolygons.
+func NewSpatialIndex(multiPolygons []MultiPolygon, opts PIPOptions) *SpatialIndex {
+	s := &SpatialIndex{opts: opts}
+	first := true
+	for region, mp := range multiPolygons {
+		for _, polygon := range mp {
+			pp := preparePolygon(polygon)
+			if pp.empty {
+				continue
+			}
+			ip := indexedPolygon{preparedPolygon: pp, region: region}
+			ip.rings = make([]ringIndex, len(pp.rings))
+			for i, ring := range pp.rings {
+				ip.rings[i] = newRingIndex(ring, opts.Epsilon)
+			}
+			s.polygons = append(s.polygons, ip)
+			if first {
+				s.bounds = pp.bbox
+				first = false
+			} else {
+				s.bounds = s.bounds.union(pp.bbox)
+			}
+		}
+	}
+	s.bounds = s.bounds.grow(opts.Epsilon)
+	s.buildGrid()
+	return s
+}
+
+func (s *SpatialIndex) Options() PIPOptions {
+	return s.opts
+}
+
+// Bounds is the extent of all indexed polygons. Polygons crossing the
+// antimeridian are unwrapped, so longitudes may exceed 180.
+func (s *SpatialIndex) Bounds() BBox {
+	return s.bounds
+}
+
+func (s *SpatialIndex) buildGrid() {
+	side := int(math.Ceil(math.Sqrt(float64(len(s.polygons))))) * 2
+	side = max(1, min(maxGridSide, side))
+	s.cols, s.rows = side, side
+	s.cellW = (s.bounds.MaxLon - s.bounds.MinLon) / float64(s.cols)
+	s.cellH = (s.bounds.MaxLat - s.bounds.MinLat) / float64(s.rows)
+	if s.cellW <= 0 {
+		s.cellW = 1
+	}
+	if s.cellH <= 0 {
+		s.cellH = 1
+	}
+	s.cells = make([][]int32, s.cols*s.rows)
+
+	for i := range s.polygons {
+		box := s.polygons[i].bbox.grow(s.opts.Epsilon)
+		// A polygon unwrapped past the antimeridian is also registered at
+		// its equivalent longitudes so points given in [-180, 180] find it.
+		for _, shift := range [...]float64{0, 360, -360} {
+			b := box.shift(shift)
+			if !b.Intersects(s.bounds) {
+				continue
+			}
+			c0, r0 := s.cell(b.MinLon, b.MinLat)
+			c1, r1 := s.cell(b.MaxLon, b.MaxLat)
+			for r := r0; r <= r1; r++ {
+				for c := c0; c <= c1; c++ {
+					cell := &s.cells[r*s.cols+c]
+					if n := len(*cell); n == 0 || (*cell)[n-1] != int32(i) {
+						*cell = append(*cell, int32(i))
+					}
+				}
+			}
+		}
+	}
+}
+
+// cell returns the grid coordinates of a position, clamped to the grid.
+func (s *SpatialIndex) cell(lon, lat float64) (int, int) {
+	c := int((lon - s.bounds.MinLon) / s.cellW)
+	r := int((lat - s.bounds.MinLat) / s.cellH)
+	return max(0, min(s.cols-1, c)), max(0, min(s.rows-1, r))
+}
+
+// Locate returns the index of the first region containing point, in the
+// order the regions were given, or false when it lies in none.
+func (s *SpatialIndex) Locate(point Point) (int, bool) {
+	if len(point) < 2 || math.IsNaN(point[0]) || math.IsNaN(point[1]) || len(s.polygons) == 0 {
+		return -1, false
+	}
+	lon := point[0]
+	if !s.bounds.Contains(lon, point[1]) {
+		switch {
+		case s.bounds.Contains(lon+360, point[1]):
+			lon += 360
+		case s.bounds.Contains(lon-360, point[1]):
+			lon -= 360
+		default:
+			return -1, false
+		}
+	}
+
+	c, r := s.cell(lon, point[1])
+	for _, i := range s.cells[r*s.cols+c] {
+		ip := &s.polygons[i]
+		loc := ip.locate(point, s.opts, func(p Point, ring int) Location {
+			return ip.rings[ring].locate(p, s.opts)
+		})
+		if includes(loc, s.opts) {
+			return ip.region, true
+		}
+	}
+	return -1, false
+}
+
+func newRingIndex(ring [][]float64, eps float64) ringIndex {
+	ri := ringIndex{ring: ring}
+	bbox, ok := ringBBox(ring)
+	if !ok || len(ring) < 3 {
+		return ri
+	}
+	ri.minLat, ri.maxLat = bbox.MinLat, bbox.MaxLat
+
+	bands := max(1, min(maxRingBand, len(ring)/edgesPerBand))
+	ri.bandH = (ri.maxLat - ri.minLat) / float64(bands)
+	if ri.bandH <= 0 {
+		bands, ri.bandH = 1, 1
+	}
+
+	// Each edge goes in every band its latitude range, widened by eps for
+	// the boundary test, overlaps. Count first, then fill.
+	span := func(i int) (int, int) {
+		a, b := ring[(i+len(ring)-1)%len(ring)], ring[i]
+		return ri.band(min(a[1], b[1])-eps, bands), ri.band(max(a[1], b[1])+eps, bands)
+	}
+	ri.bandPtr = make([]int32, bands+1)
+	for i := range ring {
+		lo, hi := span(i)
+		for b := lo; b <= hi; b++ {
+			ri.bandPtr[b+1]++
+		}
+	}
+	for b := 0; b < bands; b++ {
+		ri.bandPtr[b+1] += ri.bandPtr[b]
+	}
+	ri.edges = make([]int32, ri.bandPtr[bands])
+	next := append([]int32(nil), ri.bandPtr[:bands]...)
+	for i := range ring {
+		lo, hi := span(i)
+		for b := lo; b <= hi; b++ {
+			ri.edges[next[b]] = int32(i)
+			next[b]++
+		}
+	}
+	return ri
+}
+
+func (ri *ringIndex) band(lat float64, bands int) int {
+	return max(0, min(bands-1, int((lat-ri.minLat)/ri.bandH)))
+}
+
+// locate is locateInRing restricted to the edges in the point's band.
+func (ri *ringIndex) locate(point Point, opts PIPOptions) Location {
+	if ri.bandPtr == nil {
+		return Outside
+	}
+	y := point[1]
+	if y < ri.minLat-opts.Epsilon || y > ri.maxLat+opts.Epsilon {
+		return Outside
+	}
+	b := ri.band(y, len(ri.bandPtr)-1)
+	edges := ri.edges[ri.bandPtr[b]:ri.bandPtr[b+1]]
+	ring := ri.ring
+
+	for _, i := range edges {
+		if onSegment(point, ring[(int(i)+len(ring)-1)%len(ring)], ring[i], opts.Epsilon) {
+			return OnBoundary
+		}
+	}
+
+	in := false
+	if opts.Method == WindingNumber {
+		wn := 0
+		for _, i := range edges {
+			wn += winding(point, ring[(int(i)+len(ring)-1)%len(ring)], ring[i])
+		}
+		in = wn != 0
+	} else {
+		for _, i := range edges {
+			if rayCrosses(point, ring[(int(i)+len(ring)-1)%len(ring)], ring[i]) {
+				in = !in
+			}
+		}
+	}
+	if in {
+		return Inside
+	}
+	return Outside
+}
+
+// Locate returns the index of the first region containing point. It uses
+// the spatial index built by LoadAndExtractGeoJSON and falls back to
+// LocateLinear when there is none.
+func (e *ExtractedGeoJSON) Locate(point Point) (int, bool) {
+	if e.Index == nil {
+		return e.LocateLinear(point)
+	}
+	return e.Index.Locate(point)
+}
+
+// LocateLinear tests point against every region in turn.
+func (e *ExtractedGeoJSON) LocateLinear(point Point) (int, bool) {
+	for j, mp := range e.MultiPolygons {
+		if IsPointInMultiPolygon(point, mp) {
+			return j, true
+		}
+	}
+	return -1, false
+}
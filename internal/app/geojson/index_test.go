@@ -0,0 +1,93 @@
+package geojson
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// gridRegions builds side×side regions, each a jagged disc of vertices
+// points in its own unit cell.
+func gridRegions(side, vertices int, rng *rand.Rand) *ExtractedGeoJSON {
+	e := &ExtractedGeoJSON{MaxLon: float64(side), MaxLat: float64(side)}
+	for cy := 0; cy < side; cy++ {
+		for cx := 0; cx < side; cx++ {
+			ring := make([][]float64, vertices+1)
+			for i := 0; i < vertices; i++ {
+				angle := 2 * math.Pi * float64(i) / float64(vertices)
+				r := 0.3 + 0.15*rng.Float64()
+				ring[i] = []float64{float64(cx) + 0.5 + r*math.Cos(angle), float64(cy) + 0.5 + r*math.Sin(angle)}
+			}
+			ring[vertices] = ring[0]
+			e.MultiPolygons = append(e.MultiPolygons, MultiPolygon{{ring}})
+		}
+	}
+	e.Index = NewSpatialIndex(e.MultiPolygons, DefaultPIPOptions)
+	return e
+}
+
+func randomPoints(e *ExtractedGeoJSON, n int, rng *rand.Rand) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{
+			e.MinLon + rng.Float64()*(e.MaxLon-e.MinLon),
+			e.MinLat + rng.Float64()*(e.MaxLat-e.MinLat),
+		}
+	}
+	return points
+}
+
+func TestLocateMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	e := gridRegions(8, 48, rng)
+	for _, p := range randomPoints(e, 20000, rng) {
+		gotRegion, gotOK := e.Locate(p)
+		wantRegion, wantOK := e.LocateLinear(p)
+		if gotRegion != wantRegion || gotOK != wantOK {
+			t.Fatalf("Locate(%v) = %d, %v; LocateLinear = %d, %v", p, gotRegion, gotOK, wantRegion, wantOK)
+		}
+	}
+}
+
+const indiaPath = "../../../data/INDIA/india.geojson"
+
+// BenchmarkLocate compares the spatial index against testing every region
+// in turn, on synthetic grids of increasing size and on the India states if
+// the data set is checked out.
+func BenchmarkLocate(b *testing.B) {
+	type dataSet struct {
+		name string
+		data *ExtractedGeoJSON
+	}
+	rng := rand.New(rand.NewSource(1))
+	sets := []dataSet{
+		{"grid=4x4", gridRegions(4, 64, rng)},
+		{"grid=16x16", gridRegions(16, 64, rng)},
+		{"grid=32x32", gridRegions(32, 256, rng)},
+	}
+	if _, err := os.Stat(indiaPath); err == nil {
+		india, err := LoadAndExtractGeoJSON(indiaPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sets = append(sets, dataSet{"india", india})
+	}
+
+	for _, set := range sets {
+		points := randomPoints(set.data, 4096, rng)
+		for _, mode := range []struct {
+			name   string
+			locate func(Point) (int, bool)
+		}{
+			{"index", set.data.Locate},
+			{"linear", set.data.LocateLinear},
+		} {
+			b.Run(set.name+"/"+mode.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					mode.locate(points[i%len(points)])
+				}
+			})
+		}
+	}
+}
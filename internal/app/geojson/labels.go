@@ -0,0 +1,59 @@
+package geojson
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// LabelOptions chooses how regions are named and grouped into classes.
+type LabelOptions struct {
+	// Properties are the feature properties tried in order for a label. The
+	// first one holding a string or a number wins.
+	Properties []string
+	// Group merges regions with the same label into a single class.
+	Group bool
+}
+
+// DefaultLabelOptions covers the property names used by our datasets.
+var DefaultLabelOptions = LabelOptions{
+	Properties: []string{"NAME", "name", "ST_NM", "ISO_A3", "iso_a3", "ISO_A2", "iso_a2"},
+}
+
+// Label returns the label of a feature, or "" if none of the properties are
+// set.
+func (o LabelOptions) Label(feature *Feature) string {
+	for _, property := range o.Properties {
+		switch v := feature.Properties[property].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// Classes labels every region and assigns it a class. It returns the class
+// names, in order of first appearance, and the class of each region.
+// Regions without a label are named after their feature index and never
+// grouped.
+func (e *ExtractedGeoJSON) Classes(opts LabelOptions) ([]string, []int) {
+	var classes []string
+	regionClass := make([]int, len(e.MultiPolygons))
+	byLabel := make(map[string]int)
+	for region := range e.MultiPolygons {
+		label := opts.Label(e.Feature(region))
+		if label == "" {
+			label = fmt.Sprintf("Feature %d", e.FeatureIndices[region])
+		} else if class, ok := byLabel[label]; ok && opts.Group {
+			regionClass[region] = class
+			continue
+		}
+		byLabel[label] = len(classes)
+		regionClass[region] = len(classes)
+		classes = append(classes, label)
+	}
+	return classes, regionClass
+}
@@ -2,124 +2,367 @@ package geojson
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"math"
+	"os"
+	"strings"
 )
 
 type ExtractedGeoJSON struct {
-	MultiPolygons     []MultiPolygon
+	// MultiPolygons holds one region per feature with polygonal geometry.
+	// The position of a region is its class index.
+	MultiPolygons []MultiPolygon
+	// FeatureIndices maps a class index to the feature it came from in
+	// FeatureCollection.Features.
+	FeatureIndices []int
+	// Points and LineStrings collect the non-polygonal geometry of all
+	// features. They do not form classes.
+	Points            []Point
+	LineStrings       []LineString
 	MinLon, MinLat    float64
 	MaxLon, MaxLat    float64
 	FeatureCollection *FeatureCollection
+	// Index speeds up Locate. It is built from MultiPolygons on load.
+	Index *SpatialIndex
+}
+
+// Feature returns the feature a class index was extracted from.
+func (e *ExtractedGeoJSON) Feature(class int) *Feature {
+	return &e.FeatureCollection.Features[e.FeatureIndices[class]]
+}
+
+// ValidationError describes an invalid geometry. Feature is the index of the
+// offending feature in the collection, or -1 for the root object.
+type ValidationError struct {
+	Feature int
+	Reason  string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Feature < 0 {
+		return "invalid geojson: " + e.Reason
+	}
+	return fmt.Sprintf("invalid geojson: feature %d: %s", e.Feature, e.Reason)
 }
 
 func LoadAndExtractGeoJSON(path string) (*ExtractedGeoJSON, error) {
-	file, err := ioutil.ReadFile(path)
+	file, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read geojson file: %w", err)
 	}
+	return ExtractGeoJSON(file)
+}
 
-	var featureCollection FeatureCollection
-	if err := json.Unmarshal(file, &featureCollection); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
+// ExtractGeoJSON parses a FeatureCollection, a single Feature or a bare
+// geometry and extracts its polygonal features as regions. Every invalid
+// geometry is reported as a *ValidationError, joined into the returned
+// error.
+func ExtractGeoJSON(data []byte) (*ExtractedGeoJSON, error) {
+	featureCollection, err := parseRoot(data)
+	if err != nil {
+		return nil, err
 	}
 
-	var multiPolygons []MultiPolygon
-	minLon, minLat := 180.0, 90.0
-	maxLon, maxLat := -180.0, -90.0
+	extracted := &ExtractedGeoJSON{
+		MinLon:            180.0,
+		MinLat:            90.0,
+		MaxLon:            -180.0,
+		MaxLat:            -90.0,
+		FeatureCollection: featureCollection,
+	}
 
-	for _, feature := range featureCollection.Features {
-		var multiPolygon MultiPolygon
-		switch feature.Geometry.Type {
-		case "Polygon":
-			var polygon Polygon
-			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal polygon: %w", err)
-			}
-			multiPolygon = MultiPolygon{polygon}
-		case "MultiPolygon":
-			if err := json.Unmarshal(feature.Geometry.Coordinates, &multiPolygon); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal multipolygon: %w", err)
-			}
+	var errs []error
+	if err := validateBBox(featureCollection.BBox); err != nil {
+		errs = append(errs, &ValidationError{Feature: -1, Reason: err.Error()})
+	}
+	for i, feature := range featureCollection.Features {
+		if err := validateBBox(feature.BBox); err != nil {
+			errs = append(errs, &ValidationError{Feature: i, Reason: err.Error()})
+		}
+		if feature.Geometry == nil {
+			continue
+		}
+		var shapes shapes
+		if err := shapes.decode(feature.Geometry); err != nil {
+			errs = append(errs, &ValidationError{Feature: i, Reason: err.Error()})
+			continue
+		}
+
+		extracted.Points = append(extracted.Points, shapes.points...)
+		extracted.LineStrings = append(extracted.LineStrings, shapes.lineStrings...)
+		if len(shapes.polygons) == 0 {
+			continue
 		}
 
-		for _, polygon := range multiPolygon {
+		for _, polygon := range shapes.polygons {
 			for _, ring := range polygon {
 				for _, point := range ring {
 					lon, lat := point[0], point[1]
-					if lon < minLon {
-						minLon = lon
+					if lon < extracted.MinLon {
+						extracted.MinLon = lon
 					}
-					if lon > maxLon {
-						maxLon = lon
+					if lon > extracted.MaxLon {
+						extracted.MaxLon = lon
 					}
-					if lat < minLat {
-						minLat = lat
+					if lat < extracted.MinLat {
+						extracted.MinLat = lat
 					}
-					if lat > maxLat {
-						maxLat = lat
+					if lat > extracted.MaxLat {
+						extracted.MaxLat = lat
 					}
 				}
 			}
 		}
-		multiPolygons = append(multiPolygons, multiPolygon)
+		extracted.MultiPolygons = append(extracted.MultiPolygons, shapes.polygons)
+		extracted.FeatureIndices = append(extracted.FeatureIndices, i)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	extracted.Index = NewSpatialIndex(extracted.MultiPolygons, DefaultPIPOptions)
+	return extracted, nil
+}
+
+// parseRoot decodes the top-level object, wrapping a single Feature or
+// geometry into a FeatureCollection.
+func parseRoot(data []byte) (*FeatureCollection, error) {
+	var root struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
+	}
+
+	var featureCollection FeatureCollection
+	switch root.Type {
+	case "FeatureCollection":
+		if err := json.Unmarshal(data, &featureCollection); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
+		}
+	case "Feature":
+		var feature Feature
+		if err := json.Unmarshal(data, &feature); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
+		}
+		featureCollection = FeatureCollection{Type: "FeatureCollection", Features: []Feature{feature}}
+	case "Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection":
+		var geometry Geometry
+		if err := json.Unmarshal(data, &geometry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal geojson: %w", err)
+		}
+		featureCollection = FeatureCollection{
+			Type:     "FeatureCollection",
+			Features: []Feature{{Type: "Feature", Geometry: &geometry}},
+		}
+	default:
+		return nil, &ValidationError{Feature: -1, Reason: fmt.Sprintf("unknown root type %q", root.Type)}
+	}
+	return &featureCollection, nil
+}
+
+// shapes is the decoded content of one geometry, flattened across
+// multi-geometries and collections.
+type shapes struct {
+	points      []Point
+	lineStrings []LineString
+	polygons    MultiPolygon
+}
+
+func (s *shapes) decode(geometry *Geometry) error {
+	if err := validateBBox(geometry.BBox); err != nil {
+		return err
+	}
+
+	switch geometry.Type {
+	case "Point":
+		var point Point
+		if err := decodeCoordinates(geometry, &point); err != nil {
+			return err
+		}
+		if err := validatePosition(point); err != nil {
+			return err
+		}
+		s.points = append(s.points, point)
+	case "MultiPoint":
+		var points []Point
+		if err := decodeCoordinates(geometry, &points); err != nil {
+			return err
+		}
+		for i, point := range points {
+			if err := validatePosition(point); err != nil {
+				return fmt.Errorf("point %d: %w", i, err)
+			}
+		}
+		s.points = append(s.points, points...)
+	case "LineString":
+		var line LineString
+		if err := decodeCoordinates(geometry, &line); err != nil {
+			return err
+		}
+		if err := validateLineString(line); err != nil {
+			return err
+		}
+		s.lineStrings = append(s.lineStrings, line)
+	case "MultiLineString":
+		var lines []LineString
+		if err := decodeCoordinates(geometry, &lines); err != nil {
+			return err
+		}
+		for i, line := range lines {
+			if err := validateLineString(line); err != nil {
+				return fmt.Errorf("line %d: %w", i, err)
+			}
+		}
+		s.lineStrings = append(s.lineStrings, lines...)
+	case "Polygon":
+		var polygon Polygon
+		if err := decodeCoordinates(geometry, &polygon); err != nil {
+			return err
+		}
+		if err := validatePolygon(polygon); err != nil {
+			return err
+		}
+		s.polygons = append(s.polygons, polygon)
+	case "MultiPolygon":
+		var multiPolygon MultiPolygon
+		if err := decodeCoordinates(geometry, &multiPolygon); err != nil {
+			return err
+		}
+		for i, polygon := range multiPolygon {
+			if err := validatePolygon(polygon); err != nil {
+				return fmt.Errorf("polygon %d: %w", i, err)
+			}
+		}
+		s.polygons = append(s.polygons, multiPolygon...)
+	case "GeometryCollection":
+		for i := range geometry.Geometries {
+			if err := s.decode(&geometry.Geometries[i]); err != nil {
+				return fmt.Errorf("geometry %d: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown geometry type %q", geometry.Type)
 	}
+	return nil
+}
 
-	return &ExtractedGeoJSON{
-		MultiPolygons:     multiPolygons,
-		MinLon:            minLon,
-		MinLat:            minLat,
-		MaxLon:            maxLon,
-		MaxLat:            maxLat,
-		FeatureCollection: &featureCollection,
-	}, nil
+// decodeCoordinates unmarshals the coordinates into v, whose type fixes the
+// nesting depth the geometry type requires. JSON cannot spell NaN or
+// infinity, so a number that overflows float64 is the only non-finite
+// coordinate it can carry.
+func decodeCoordinates(geometry *Geometry, v any) error {
+	if len(geometry.Coordinates) == 0 {
+		return fmt.Errorf("%s has no coordinates", geometry.Type)
+	}
+	if err := json.Unmarshal(geometry.Coordinates, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && strings.HasPrefix(typeErr.Value, "number ") {
+			return fmt.Errorf("coordinate %s is not finite", strings.TrimPrefix(typeErr.Value, "number "))
+		}
+		return fmt.Errorf("coordinates are not nested as a %s", geometry.Type)
+	}
+	return nil
+}
+
+func validatePosition(position []float64) error {
+	if len(position) < 2 {
+		return fmt.Errorf("position has %d values, need at least 2", len(position))
+	}
+	for _, v := range position {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("position is not finite")
+		}
+	}
+	return nil
 }
 
+func validateLineString(line LineString) error {
+	if len(line) < 2 {
+		return fmt.Errorf("line string has %d positions, need at least 2", len(line))
+	}
+	for i, position := range line {
+		if err := validatePosition(position); err != nil {
+			return fmt.Errorf("position %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validatePolygon checks that every ring is a closed ring of at least four
+// positions and that every hole starts inside or on the outer ring.
+func validatePolygon(polygon Polygon) error {
+	if len(polygon) == 0 {
+		return errors.New("polygon has no rings")
+	}
+	for r, ring := range polygon {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring %d has %d positions, need at least 4", r, len(ring))
+		}
+		for i, position := range ring {
+			if err := validatePosition(position); err != nil {
+				return fmt.Errorf("ring %d position %d: %w", r, i, err)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("ring %d is not closed", r)
+		}
+	}
+	for r, hole := range polygon[1:] {
+		if LocatePointInPolygon(Point(hole[0]), Polygon{polygon[0]}, DefaultPIPOptions) == Outside {
+			return fmt.Errorf("hole ring %d lies outside the outer ring", r+1)
+		}
+	}
+	return nil
+}
+
+func validateBBox(bbox []float64) error {
+	if bbox == nil {
+		return nil
+	}
+	if len(bbox) != 4 && len(bbox) != 6 {
+		return fmt.Errorf("bbox has %d values, need 4 or 6", len(bbox))
+	}
+	half := len(bbox) / 2
+	// Longitude may wrap across the antimeridian, so only the other axes
+	// must be ordered.
+	for i := 1; i < half; i++ {
+		if bbox[i] > bbox[half+i] {
+			return errors.New("bbox minimum exceeds its maximum")
+		}
+	}
+	return nil
+}
 
 // GeoJSON structs
 type Point []float64
 
+type LineString [][]float64
+
 type Polygon [][][]float64
 
 type MultiPolygon [][][][]float64
 
+// Geometry is any GeoJSON geometry. Coordinates is left raw until its type
+// is known; a GeometryCollection uses Geometries instead.
 type Geometry struct {
 	Type        string          `json:"type"`
-	Coordinates json.RawMessage `json:"coordinates"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []Geometry      `json:"geometries,omitempty"`
+	BBox        []float64       `json:"bbox,omitempty"`
 }
 
 type Feature struct {
 	Type       string                 `json:"type"`
-	Geometry   Geometry               `json:"geometry"`
+	Geometry   *Geometry              `json:"geometry"`
 	Properties map[string]interface{} `json:"properties"`
+	BBox       []float64              `json:"bbox,omitempty"`
 }
 
 type FeatureCollection struct {
 	Type     string    `json:"type"`
 	Features []Feature `json:"features"`
-}
-
-// Point in polygon check (Ray Casting algorithm)
-func IsPointInPolygon(point Point, polygon Polygon) bool {
-	in := false
-	for _, ring := range polygon {
-		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
-			p1 := ring[i]
-			p2 := ring[j]
-			if (p1[1] > point[1]) != (p2[1] > point[1]) &&
-				(point[0] < (p2[0]-p1[0])*(point[1]-p1[1])/(p2[1]-p1[1])+p1[0]) {
-				in = !in
-			}
-		}
-	}
-	return in
-}
-
-func IsPointInMultiPolygon(point Point, multiPolygon MultiPolygon) bool {
-	for _, polygon := range multiPolygon {
-		if IsPointInPolygon(point, polygon) {
-			return true
-		}
-	}
-	return false
+	BBox     []float64 `json:"bbox,omitempty"`
 }
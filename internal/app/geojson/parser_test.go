@@ -0,0 +1,259 @@
+package geojson
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+const (
+	squareJSON    = `[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`
+	farSquareJSON = `[[[20,20],[30,20],[30,30],[20,30],[20,20]]]`
+)
+
+func feature(geometry string) string {
+	return `{"type":"Feature","properties":{},"geometry":` + geometry + `}`
+}
+
+func collection(features ...string) string {
+	s := `{"type":"FeatureCollection","features":[`
+	for i, f := range features {
+		if i > 0 {
+			s += ","
+		}
+		s += f
+	}
+	return s + `]}`
+}
+
+// validationErrors unpacks the errors joined by ExtractGeoJSON.
+func validationErrors(t *testing.T, err error) []ValidationError {
+	t.Helper()
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var got []ValidationError
+	for _, err := range errs {
+		var v *ValidationError
+		if !errors.As(err, &v) {
+			t.Fatalf("error %v is not a *ValidationError", err)
+		}
+		got = append(got, *v)
+	}
+	return got
+}
+
+func TestExtractGeoJSONRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []ValidationError
+	}{
+		{
+			"unclosed ring",
+			collection(
+				feature(`{"type":"Polygon","coordinates":`+squareJSON+`}`),
+				feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}`),
+			),
+			[]ValidationError{{1, "ring 0 is not closed"}},
+		},
+		{
+			"ring of three positions",
+			collection(feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[0,0]]]}`)),
+			[]ValidationError{{0, "ring 0 has 3 positions, need at least 4"}},
+		},
+		{
+			"hole with three positions",
+			collection(feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[4,4],[6,4],[4,4]]]}`)),
+			[]ValidationError{{0, "ring 1 has 3 positions, need at least 4"}},
+		},
+		{
+			"hole outside the outer ring",
+			collection(feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[20,20],[30,20],[30,30],[20,30],[20,20]]]}`)),
+			[]ValidationError{{0, "hole ring 1 lies outside the outer ring"}},
+		},
+		{
+			"non-finite coordinate",
+			collection(feature(`{"type":"Polygon","coordinates":[[[0,0],[1e400,0],[10,10],[0,0]]]}`)),
+			[]ValidationError{{0, "coordinate 1e400 is not finite"}},
+		},
+		{
+			"short position",
+			collection(feature(`{"type":"Point","coordinates":[1]}`)),
+			[]ValidationError{{0, "position has 1 values, need at least 2"}},
+		},
+		{
+			"wrong nesting",
+			collection(feature(`{"type":"Polygon","coordinates":[[0,0],[10,0],[10,10],[0,0]]}`)),
+			[]ValidationError{{0, "coordinates are not nested as a Polygon"}},
+		},
+		{
+			"missing coordinates",
+			collection(feature(`{"type":"Polygon"}`)),
+			[]ValidationError{{0, "Polygon has no coordinates"}},
+		},
+		{
+			"unsupported geometry type",
+			collection(feature(`{"type":"Circle","coordinates":[0,0]}`)),
+			[]ValidationError{{0, `unknown geometry type "Circle"`}},
+		},
+		{
+			"unsupported geometry in a collection",
+			collection(feature(`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[0,0]},{"type":"Circle"}]}`)),
+			[]ValidationError{{0, `geometry 1: unknown geometry type "Circle"`}},
+		},
+		{
+			"unsupported root type",
+			`{"type":"Topology","objects":{}}`,
+			[]ValidationError{{-1, `unknown root type "Topology"`}},
+		},
+		{
+			"invalid root bbox",
+			`{"type":"FeatureCollection","bbox":[0,10,10,0],"features":[]}`,
+			[]ValidationError{{-1, "bbox minimum exceeds its maximum"}},
+		},
+		{
+			"invalid polygon of a MultiPolygon",
+			collection(feature(`{"type":"MultiPolygon","coordinates":[` + squareJSON + `,[[[20,20],[30,20],[30,30],[20,30]]]]}`)),
+			[]ValidationError{{0, "polygon 1: ring 0 is not closed"}},
+		},
+		{
+			"invalid bare Polygon",
+			`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}`,
+			[]ValidationError{{0, "ring 0 is not closed"}},
+		},
+		{
+			"invalid bare Feature",
+			feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[0,0]]]}`),
+			[]ValidationError{{0, "ring 0 has 3 positions, need at least 4"}},
+		},
+		{
+			"every invalid feature is reported",
+			collection(
+				feature(`{"type":"Polygon","coordinates":[[[0,0],[10,0],[0,0]]]}`),
+				feature(`{"type":"Polygon","coordinates":`+squareJSON+`}`),
+				feature(`{"type":"Circle"}`),
+			),
+			[]ValidationError{
+				{0, "ring 0 has 3 positions, need at least 4"},
+				{2, `unknown geometry type "Circle"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extracted, err := ExtractGeoJSON([]byte(tt.data))
+			if err == nil {
+				t.Fatalf("ExtractGeoJSON succeeded with %d regions", len(extracted.MultiPolygons))
+			}
+			got := validationErrors(t, err)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractGeoJSONFeatureIndices(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		indices     []int
+		polygons    []int
+		points      int
+		lineStrings int
+	}{
+		{
+			"bare Polygon",
+			`{"type":"Polygon","coordinates":` + squareJSON + `}`,
+			[]int{0}, []int{1}, 0, 0,
+		},
+		{
+			"bare MultiPolygon",
+			`{"type":"MultiPolygon","coordinates":[` + squareJSON + `,` + farSquareJSON + `]}`,
+			[]int{0}, []int{2}, 0, 0,
+		},
+		{
+			"bare Point",
+			`{"type":"Point","coordinates":[1,2]}`,
+			nil, nil, 1, 0,
+		},
+		{
+			"bare Feature",
+			feature(`{"type":"Polygon","coordinates":` + squareJSON + `}`),
+			[]int{0}, []int{1}, 0, 0,
+		},
+		{
+			"bare GeometryCollection",
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"Polygon","coordinates":` + squareJSON + `}]}`,
+			[]int{0}, []int{1}, 1, 0,
+		},
+		{
+			"mixed collection",
+			collection(
+				feature(`{"type":"Point","coordinates":[1,2]}`),
+				feature(`{"type":"MultiPolygon","coordinates":[`+squareJSON+`,`+farSquareJSON+`]}`),
+				feature(`null`),
+				feature(`{"type":"LineString","coordinates":[[0,0],[1,1]]}`),
+				feature(`{"type":"Polygon","coordinates":`+farSquareJSON+`}`),
+			),
+			[]int{1, 4}, []int{2, 1}, 1, 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extracted, err := ExtractGeoJSON([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(extracted.FeatureIndices, tt.indices) {
+				t.Errorf("FeatureIndices = %v, want %v", extracted.FeatureIndices, tt.indices)
+			}
+			var polygons []int
+			for _, region := range extracted.MultiPolygons {
+				polygons = append(polygons, len(region))
+			}
+			if !slices.Equal(polygons, tt.polygons) {
+				t.Errorf("polygons per region = %v, want %v", polygons, tt.polygons)
+			}
+			if len(extracted.Points) != tt.points || len(extracted.LineStrings) != tt.lineStrings {
+				t.Errorf("got %d points and %d line strings, want %d and %d",
+					len(extracted.Points), len(extracted.LineStrings), tt.points, tt.lineStrings)
+			}
+			for class, i := range extracted.FeatureIndices {
+				if extracted.Feature(class) != &extracted.FeatureCollection.Features[i] {
+					t.Errorf("Feature(%d) is not feature %d", class, i)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractGeoJSONBounds(t *testing.T) {
+	data := collection(
+		feature(`{"type":"Point","coordinates":[-50,-50]}`),
+		feature(`{"type":"MultiPolygon","coordinates":[`+squareJSON+`,`+farSquareJSON+`]}`),
+	)
+	extracted, err := ExtractGeoJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []float64{extracted.MinLon, extracted.MinLat, extracted.MaxLon, extracted.MaxLat}
+	if want := []float64{0, 0, 30, 30}; !slices.Equal(got, want) {
+		t.Errorf("bounds = %v, want %v; points must not widen them", got, want)
+	}
+}
+
+func TestValidatePolygonNonFinite(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		polygon := Polygon{{{0, 0}, {10, 0}, {10, v}, {0, 0}}}
+		err := validatePolygon(polygon)
+		if err == nil || err.Error() != "ring 0 position 2: position is not finite" {
+			t.Errorf("validatePolygon with %v = %v", v, err)
+		}
+	}
+}
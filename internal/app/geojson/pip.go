@@ -0,0 +1,316 @@
+package geojson
+
+import "math"
+
+// Location is where a point lies relative to a ring or polygon.
+type Location int
+
+const (
+	Outside Location = iota
+	Inside
+	OnBoundary
+)
+
+func (l Location) String() string {
+	switch l {
+	case Inside:
+		return "inside"
+	case OnBoundary:
+		return "boundary"
+	default:
+		return "outside"
+	}
+}
+
+// PIPMethod selects the point-in-ring test.
+type PIPMethod int
+
+const (
+	// RayCasting counts edge crossings of a ray towards +lon (even-odd rule).
+	RayCasting PIPMethod = iota
+	// WindingNumber sums the signed turns of the ring around the point
+	// (non-zero rule), which also gives sensible answers for
+	// self-overlapping rings.
+	WindingNumber
+)
+
+// PIPOptions configures the point-in-polygon tests.
+type PIPOptions struct {
+	Method PIPMethod
+	// IncludeBoundary counts points on an outer ring or a hole ring as part
+	// of the polygon. When false they are outside.
+	IncludeBoundary bool
+	// Epsilon is the distance in degrees within which a point is treated as
+	// lying on an edge.
+	Epsilon float64
+}
+
+// DefaultPIPOptions is what IsPointInPolygon uses.
+var DefaultPIPOptions = PIPOptions{
+	Method:          RayCasting,
+	IncludeBoundary: true,
+	Epsilon:         1e-12,
+}
+
+// IsPointInPolygon reports whether point lies in polygon using
+// DefaultPIPOptions.
+func IsPointInPolygon(point Point, polygon Polygon) bool {
+	return PointInPolygon(point, polygon, DefaultPIPOptions)
+}
+
+func IsPointInMultiPolygon(point Point, multiPolygon MultiPolygon) bool {
+	return PointInMultiPolygon(point, multiPolygon, DefaultPIPOptions)
+}
+
+func PointInPolygon(point Point, polygon Polygon, opts PIPOptions) bool {
+	return includes(LocatePointInPolygon(point, polygon, opts), opts)
+}
+
+func PointInMultiPolygon(point Point, multiPolygon MultiPolygon, opts PIPOptions) bool {
+	for _, polygon := range multiPolygon {
+		if PointInPolygon(point, polygon, opts) {
+			return true
+		}
+	}
+	return false
+}
+
+func includes(loc Location, opts PIPOptions) bool {
+	return loc == Inside || (loc == OnBoundary && opts.IncludeBoundary)
+}
+
+// LocatePointInPolygon classifies point against a polygon whose first ring
+// is the outer boundary and whose remaining rings are holes, as in RFC 7946.
+// Ring orientation is ignored. A point is inside when it is inside the outer
+// ring and not inside any hole; the boundary of a hole is part of the
+// polygon's boundary. Holes lying outside the outer ring have no effect,
+// unlike the plain even-odd rule over all rings.
+//
+// Rings that cross the antimeridian, i.e. have an edge spanning more than
+// 180 degrees of longitude, are unwrapped into a continuous longitude range
+// and the point is tested at its equivalent longitudes 360 degrees apart.
+func LocatePointInPolygon(point Point, polygon Polygon, opts PIPOptions) Location {
+	if len(point) < 2 || math.IsNaN(point[0]) || math.IsNaN(point[1]) {
+		return Outside
+	}
+	pp := preparePolygon(polygon)
+	return pp.locate(point, opts, func(p Point, ring int) Location {
+		return locateInRing(p, pp.rings[ring], opts)
+	})
+}
+
+// preparedPolygon is a polygon with its rings unwrapped across the
+// antimeridian and its holes moved into the same longitude range as the
+// outer ring.
+type preparedPolygon struct {
+	rings [][][]float64
+	bbox  BBox
+	empty bool
+}
+
+func preparePolygon(polygon Polygon) preparedPolygon {
+	if len(polygon) == 0 {
+		return preparedPolygon{empty: true}
+	}
+	outer := unwrapRing(polygon[0])
+	bbox, ok := ringBBox(outer)
+	if !ok {
+		return preparedPolygon{empty: true}
+	}
+	center := (bbox.MinLon + bbox.MaxLon) / 2
+
+	rings := make([][][]float64, 0, len(polygon))
+	rings = append(rings, outer)
+	for _, ring := range polygon[1:] {
+		hole := unwrapRing(ring)
+		if hb, ok := ringBBox(hole); ok {
+			if shift := 360 * math.Round((center-(hb.MinLon+hb.MaxLon)/2)/360); shift != 0 {
+				hole = shiftRing(hole, shift)
+			}
+		}
+		rings = append(rings, hole)
+	}
+	return preparedPolygon{rings: rings, bbox: bbox}
+}
+
+// locate applies the outer ring and hole rules, with inRing classifying the
+// point against one ring by index. The point is tried at each of its
+// longitudes 360 degrees apart that fall within the outer ring's range.
+func (pp *preparedPolygon) locate(point Point, opts PIPOptions, inRing func(p Point, ring int) Location) Location {
+	if pp.empty {
+		return Outside
+	}
+	if point[1] < pp.bbox.MinLat-opts.Epsilon || point[1] > pp.bbox.MaxLat+opts.Epsilon {
+		return Outside
+	}
+
+	best := Outside
+	for _, shift := range [...]float64{0, 360, -360} {
+		lon := point[0] + shift
+		if lon < pp.bbox.MinLon-opts.Epsilon || lon > pp.bbox.MaxLon+opts.Epsilon {
+			continue
+		}
+		p := Point{lon, point[1]}
+		loc := inRing(p, 0)
+		for h := 1; loc == Inside && h < len(pp.rings); h++ {
+			switch inRing(p, h) {
+			case Inside:
+				loc = Outside
+			case OnBoundary:
+				loc = OnBoundary
+			}
+		}
+		if loc == Inside {
+			return Inside
+		}
+		if loc == OnBoundary {
+			best = OnBoundary
+		}
+	}
+	return best
+}
+
+// LocatePointInRing classifies point against a single ring. The ring may or
+// may not repeat its first vertex at the end.
+func LocatePointInRing(point Point, ring [][]float64, opts PIPOptions) Location {
+	if len(point) < 2 {
+		return Outside
+	}
+	return locateInRing(point, unwrapRing(ring), opts)
+}
+
+func locateInRing(point Point, ring [][]float64, opts PIPOptions) Location {
+	if len(ring) < 3 {
+		return Outside
+	}
+	if onRingBoundary(point, ring, opts.Epsilon) {
+		return OnBoundary
+	}
+	var in bool
+	if opts.Method == WindingNumber {
+		in = windingNumber(point, ring) != 0
+	} else {
+		in = rayCast(point, ring)
+	}
+	if in {
+		return Inside
+	}
+	return Outside
+}
+
+// rayCast is the even-odd test. Edges are half-open in latitude, so a ray
+// through a vertex is counted once and zero-length or horizontal edges are
+// never counted.
+func rayCast(point Point, ring [][]float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if rayCrosses(point, ring[j], ring[i]) {
+			in = !in
+		}
+	}
+	return in
+}
+
+func rayCrosses(point Point, a, b []float64) bool {
+	x, y := point[0], point[1]
+	return (b[1] > y) != (a[1] > y) &&
+		x < (a[0]-b[0])*(y-b[1])/(a[1]-b[1])+b[0]
+}
+
+// windingNumber is Sunday's winding number: upward edges with the point on
+// their left add one, downward edges with the point on their right take one
+// away.
+func windingNumber(point Point, ring [][]float64) int {
+	wn := 0
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		wn += winding(point, ring[j], ring[i])
+	}
+	return wn
+}
+
+func winding(point Point, a, b []float64) int {
+	x, y := point[0], point[1]
+	side := (b[0]-a[0])*(y-a[1]) - (x-a[0])*(b[1]-a[1])
+	if a[1] <= y {
+		if b[1] > y && side > 0 {
+			return 1
+		}
+	} else if b[1] <= y && side < 0 {
+		return -1
+	}
+	return 0
+}
+
+func onRingBoundary(point Point, ring [][]float64, eps float64) bool {
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if onSegment(point, ring[j], ring[i], eps) {
+			return true
+		}
+	}
+	return false
+}
+
+// onSegment reports whether p is within eps of the segment ab. Degenerate
+// segments reduce to a distance check against a.
+func onSegment(p Point, a, b []float64, eps float64) bool {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	px, py := p[0]-a[0], p[1]-a[1]
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return px*px+py*py <= eps*eps
+	}
+	t := (px*dx + py*dy) / lengthSq
+	t = max(0, min(1, t))
+	ex, ey := px-t*dx, py-t*dy
+	return ex*ex+ey*ey <= eps*eps
+}
+
+// unwrapRing returns ring with longitudes made continuous across the
+// antimeridian. Rings that do not cross it are returned as is.
+func unwrapRing(ring [][]float64) [][]float64 {
+	crosses := false
+	for i := 1; i < len(ring); i++ {
+		if math.Abs(ring[i][0]-ring[i-1][0]) > 180 {
+			crosses = true
+			break
+		}
+	}
+	if !crosses {
+		return ring
+	}
+
+	out := make([][]float64, len(ring))
+	offset := 0.0
+	for i, p := range ring {
+		if i > 0 {
+			switch d := p[0] - ring[i-1][0]; {
+			case d > 180:
+				offset -= 360
+			case d < -180:
+				offset += 360
+			}
+		}
+		out[i] = []float64{p[0] + offset, p[1]}
+	}
+	return out
+}
+
+func shiftRing(ring [][]float64, shift float64) [][]float64 {
+	out := make([][]float64, len(ring))
+	for i, p := range ring {
+		out[i] = []float64{p[0] + shift, p[1]}
+	}
+	return out
+}
+
+// ringBBox returns the bounding box of ring, or false for an empty ring.
+func ringBBox(ring [][]float64) (BBox, bool) {
+	if len(ring) == 0 {
+		return BBox{}, false
+	}
+	b := BBox{MinLon: ring[0][0], MinLat: ring[0][1], MaxLon: ring[0][0], MaxLat: ring[0][1]}
+	for _, p := range ring[1:] {
+		b = b.extend(p[0], p[1])
+	}
+	return b, true
+}
@@ -0,0 +1,232 @@
+package geojson
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	square = [][]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	// centreHole is a closed hole in the middle of square.
+	centreHole = [][]float64{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}}
+	// edgeHole shares part of the left edge of square.
+	edgeHole = [][]float64{{0, 4}, {3, 4}, {3, 6}, {0, 6}, {0, 4}}
+	// farHole lies entirely outside square.
+	farHole = [][]float64{{20, 20}, {30, 20}, {30, 30}, {20, 30}, {20, 20}}
+
+	// dateline spans 170°E to 170°W across the antimeridian.
+	dateline = [][]float64{{170, -10}, {-170, -10}, {-170, 10}, {170, 10}, {170, -10}}
+	// westHole is written in western longitudes and only lines up with the
+	// unwrapped dateline ring after a 360 degree shift.
+	westHole = [][]float64{{-178, -2}, {-176, -2}, {-176, 2}, {-178, 2}, {-178, -2}}
+	// datelineHole crosses the antimeridian itself.
+	datelineHole = [][]float64{{178, -2}, {-178, -2}, {-178, 2}, {178, 2}, {178, -2}}
+
+	// steps has a repeated vertex and a horizontal edge at y = 5.
+	steps = [][]float64{{0, 0}, {10, 0}, {10, 0}, {10, 5}, {15, 5}, {15, 10}, {0, 10}, {0, 0}}
+	// diamond is unclosed and has vertices at the height of rays through its
+	// middle.
+	diamond = [][]float64{{5, 0}, {10, 5}, {5, 10}, {0, 5}}
+	// notch has a reflex vertex at (5, 5).
+	notch = [][]float64{{0, 0}, {10, 0}, {5, 5}, {10, 10}, {0, 10}}
+)
+
+var methods = []struct {
+	name   string
+	method PIPMethod
+}{
+	{"ray casting", RayCasting},
+	{"winding number", WindingNumber},
+}
+
+func TestLocatePointInPolygon(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name    string
+		polygon Polygon
+		point   Point
+		want    Location
+	}{
+		{"inside", Polygon{square}, Point{2, 2}, Inside},
+		{"outside", Polygon{square}, Point{11, 5}, Outside},
+		{"on outer edge", Polygon{square}, Point{0, 5}, OnBoundary},
+		{"on outer vertex", Polygon{square}, Point{10, 10}, OnBoundary},
+
+		{"in hole", Polygon{square, centreHole}, Point{5, 5}, Outside},
+		{"around hole", Polygon{square, centreHole}, Point{2, 5}, Inside},
+		{"on hole edge", Polygon{square, centreHole}, Point{4, 5}, OnBoundary},
+		{"on hole vertex", Polygon{square, centreHole}, Point{6, 6}, OnBoundary},
+
+		{"in hole outside outer ring", Polygon{square, farHole}, Point{25, 25}, Outside},
+		{"inside with hole outside outer ring", Polygon{square, farHole}, Point{5, 5}, Inside},
+
+		{"in hole touching outer ring", Polygon{square, edgeHole}, Point{1, 5}, Outside},
+		{"on shared edge", Polygon{square, edgeHole}, Point{0, 5}, OnBoundary},
+		{"on outer edge beside hole", Polygon{square, edgeHole}, Point{0, 2}, OnBoundary},
+		{"on hole edge inside outer ring", Polygon{square, edgeHole}, Point{3, 5}, OnBoundary},
+		{"beside hole touching outer ring", Polygon{square, edgeHole}, Point{1, 2}, Inside},
+
+		{"east of antimeridian", Polygon{dateline}, Point{175, 0}, Inside},
+		{"west of antimeridian", Polygon{dateline}, Point{-175, 0}, Inside},
+		{"on antimeridian", Polygon{dateline}, Point{180, 0}, Inside},
+		{"on antimeridian as -180", Polygon{dateline}, Point{-180, 0}, Inside},
+		{"prime meridian", Polygon{dateline}, Point{0, 0}, Outside},
+		{"beyond east edge", Polygon{dateline}, Point{165, 0}, Outside},
+		{"beyond west edge", Polygon{dateline}, Point{-165, 0}, Outside},
+		{"on antimeridian edge", Polygon{dateline}, Point{180, 10}, OnBoundary},
+		{"in shifted hole", Polygon{dateline, westHole}, Point{-177, 0}, Outside},
+		{"in shifted hole, unwrapped longitude", Polygon{dateline, westHole}, Point{183, 0}, Outside},
+		{"on shifted hole edge", Polygon{dateline, westHole}, Point{-178, 0}, OnBoundary},
+		{"beside shifted hole", Polygon{dateline, westHole}, Point{-179, 0}, Inside},
+		{"in antimeridian hole, east", Polygon{dateline, datelineHole}, Point{179.5, 0}, Outside},
+		{"in antimeridian hole, west", Polygon{dateline, datelineHole}, Point{-179.5, 0}, Outside},
+		{"beside antimeridian hole", Polygon{dateline, datelineHole}, Point{177, 0}, Inside},
+
+		{"ray along horizontal edge", Polygon{steps}, Point{5, 5}, Inside},
+		{"above horizontal edge", Polygon{steps}, Point{12, 7}, Inside},
+		{"below horizontal edge", Polygon{steps}, Point{12, 3}, Outside},
+		{"on horizontal edge", Polygon{steps}, Point{12, 5}, OnBoundary},
+		{"past horizontal edge", Polygon{steps}, Point{16, 5}, Outside},
+		{"on repeated vertex", Polygon{steps}, Point{10, 0}, OnBoundary},
+		{"beside repeated vertex", Polygon{steps}, Point{9, 1}, Inside},
+
+		{"ray through far vertex", Polygon{diamond}, Point{2, 5}, Inside},
+		{"ray through both vertices", Polygon{diamond}, Point{-1, 5}, Outside},
+		{"ray through top vertex", Polygon{diamond}, Point{-1, 10}, Outside},
+		{"ray through reflex vertex", Polygon{notch}, Point{2, 5}, Inside},
+		{"in notch", Polygon{notch}, Point{7, 5}, Outside},
+		{"on reflex vertex", Polygon{notch}, Point{5, 5}, OnBoundary},
+
+		{"NaN longitude", Polygon{square}, Point{nan, 5}, Outside},
+		{"NaN latitude", Polygon{square}, Point{5, nan}, Outside},
+		{"NaN point", Polygon{square}, Point{nan, nan}, Outside},
+		{"short point", Polygon{square}, Point{5}, Outside},
+		{"empty polygon", Polygon{}, Point{5, 5}, Outside},
+		{"degenerate ring", Polygon{{{0, 0}, {10, 0}}}, Point{5, 0}, Outside},
+	}
+	for _, m := range methods {
+		for _, tt := range tests {
+			t.Run(m.name+"/"+tt.name, func(t *testing.T) {
+				opts := DefaultPIPOptions
+				opts.Method = m.method
+				if got := LocatePointInPolygon(tt.point, tt.polygon, opts); got != tt.want {
+					t.Errorf("LocatePointInPolygon(%v) = %v, want %v", tt.point, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPointInPolygonIncludeBoundary(t *testing.T) {
+	tests := []struct {
+		name               string
+		point              Point
+		included, excluded bool
+	}{
+		{"interior", Point{2, 2}, true, true},
+		{"hole", Point{5, 5}, false, false},
+		{"outer boundary", Point{0, 5}, true, false},
+		{"hole boundary", Point{4, 5}, true, false},
+		{"hole vertex", Point{4, 4}, true, false},
+		{"exterior", Point{-1, 5}, false, false},
+		{"NaN", Point{math.NaN(), 5}, false, false},
+	}
+	polygon := Polygon{square, centreHole}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultPIPOptions
+			opts.IncludeBoundary = true
+			if got := PointInPolygon(tt.point, polygon, opts); got != tt.included {
+				t.Errorf("with boundary: PointInPolygon(%v) = %v, want %v", tt.point, got, tt.included)
+			}
+			opts.IncludeBoundary = false
+			if got := PointInPolygon(tt.point, polygon, opts); got != tt.excluded {
+				t.Errorf("without boundary: PointInPolygon(%v) = %v, want %v", tt.point, got, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestPointInMultiPolygonSharedEdge(t *testing.T) {
+	left := Polygon{square}
+	right := Polygon{{{10, 0}, {20, 0}, {20, 10}, {10, 10}, {10, 0}}}
+	multi := MultiPolygon{left, right}
+
+	for _, p := range []Point{{10, 5}, {10, 0}, {10, 10}} {
+		for _, polygon := range multi {
+			if got := LocatePointInPolygon(p, polygon, DefaultPIPOptions); got != OnBoundary {
+				t.Errorf("LocatePointInPolygon(%v) = %v, want boundary", p, got)
+			}
+		}
+		opts := DefaultPIPOptions
+		if !PointInMultiPolygon(p, multi, opts) {
+			t.Errorf("%v on the shared edge is not in the multipolygon with boundaries", p)
+		}
+		opts.IncludeBoundary = false
+		if PointInMultiPolygon(p, multi, opts) {
+			t.Errorf("%v on the shared edge is in the multipolygon without boundaries", p)
+		}
+	}
+	for _, p := range []Point{{5, 5}, {15, 5}} {
+		if !IsPointInMultiPolygon(p, multi) {
+			t.Errorf("%v is not in the multipolygon", p)
+		}
+	}
+}
+
+func TestLocatePointInRingClosedAndUnclosed(t *testing.T) {
+	closedRing := square
+	openRing := square[:len(square)-1]
+	points := []Point{{5, 5}, {0, 5}, {10, 0}, {5, 10}, {-1, 5}, {11, 11}, {5, 0}}
+	for _, m := range methods {
+		opts := DefaultPIPOptions
+		opts.Method = m.method
+		for _, p := range points {
+			closed := LocatePointInRing(p, closedRing, opts)
+			open := LocatePointInRing(p, openRing, opts)
+			if closed != open {
+				t.Errorf("%s: %v is %v in the closed ring but %v in the unclosed one", m.name, p, closed, open)
+			}
+		}
+	}
+	if got := LocatePointInRing(Point{5, 5}, openRing, DefaultPIPOptions); got != Inside {
+		t.Errorf("centre of unclosed ring is %v", got)
+	}
+	if got := LocatePointInRing(Point{math.NaN(), 5}, closedRing, DefaultPIPOptions); got != Outside {
+		t.Errorf("NaN point is %v", got)
+	}
+}
+
+func TestSelfOverlappingRing(t *testing.T) {
+	// A pentagram covers its centre twice: the even-odd rule leaves the
+	// centre out, the non-zero rule fills it.
+	star := make([][]float64, 5)
+	for i := range star {
+		angle := math.Pi/2 + float64(2*i)*2*math.Pi/5
+		star[i] = []float64{10 * math.Cos(angle), 10 * math.Sin(angle)}
+	}
+	tests := []struct {
+		name         string
+		point        Point
+		ray, winding Location
+	}{
+		{"centre", Point{0, 0}, Outside, Inside},
+		{"top point", Point{0, 8}, Inside, Inside},
+		{"outside", Point{0, 11}, Outside, Outside},
+		{"between points", Point{5, 6}, Outside, Outside},
+		{"on vertex", Point{0, 10}, OnBoundary, OnBoundary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultPIPOptions
+			opts.Method = RayCasting
+			if got := LocatePointInRing(tt.point, star, opts); got != tt.ray {
+				t.Errorf("ray casting = %v, want %v", got, tt.ray)
+			}
+			opts.Method = WindingNumber
+			if got := LocatePointInRing(tt.point, star, opts); got != tt.winding {
+				t.Errorf("winding number = %v, want %v", got, tt.winding)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// The binary format is a 4 byte magic, a version byte, rows and cols as
+// little-endian uint64 and then the row-major float64 data, little-endian.
+var binaryMagic = [4]byte{'N', 'N', 'M', 'X'}
+
+const binaryVersion = 1
+
+// maxReadElements bounds the number of elements ReadBinary and ReadNPY will
+// allocate for, so that a corrupt header cannot request gigabytes of memory.
+const maxReadElements = 1 << 28
+
+func WriteBinary(w io.Writer, m *Matrix) error {
+	header := make([]byte, 0, 21)
+	header = append(header, binaryMagic[:]...)
+	header = append(header, binaryVersion)
+	header = binary.LittleEndian.AppendUint64(header, uint64(m.Rows))
+	header = binary.LittleEndian.AppendUint64(header, uint64(m.Cols))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 8*1024)
+	for _, v := range m.Data {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		if len(buf) == cap(buf) {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func ReadBinary(r io.Reader) (*Matrix, error) {
+	header := make([]byte, 21)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], binaryMagic[:]) {
+		return nil, errors.New("Not a binary matrix file")
+	}
+	if header[4] != binaryVersion {
+		return nil, errors.New("Unsupported binary matrix version")
+	}
+	rows := binary.LittleEndian.Uint64(header[5:13])
+	cols := binary.LittleEndian.Uint64(header[13:21])
+	// Empty matrices are valid, as WriteBinary writes them, but each
+	// dimension must still fit in an int.
+	if rows > maxReadElements || cols > maxReadElements || (rows != 0 && cols > maxReadElements/rows) {
+		return nil, errors.New("Binary matrix dimensions are too large")
+	}
+
+	raw := make([]byte, 8*rows*cols)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:]))
+	}
+	return NewMatrix(int(rows), int(cols), data), nil
+}
+
+func (m *Matrix) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *Matrix) UnmarshalBinary(data []byte) error {
+	out, err := ReadBinary(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*m = *out
+	return nil
+}
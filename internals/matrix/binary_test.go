@@ -0,0 +1,275 @@
+package matrix
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadBinaryRejectsBadDimensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows, cols uint64
+	}{
+		{"overflowing product", 1 << 32, 1 << 32},
+		{"too large", 1 << 20, 1 << 20},
+		{"cols too large without rows", 0, 1 << 63},
+		{"rows too large without cols", 1 << 63, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := append([]byte(nil), binaryMagic[:]...)
+			header = append(header, binaryVersion)
+			header = binary.LittleEndian.AppendUint64(header, tt.rows)
+			header = binary.LittleEndian.AppendUint64(header, tt.cols)
+			if _, err := ReadBinary(bytes.NewReader(header)); err == nil {
+				t.Errorf("ReadBinary accepted a %dx%d header", tt.rows, tt.cols)
+			}
+		})
+	}
+}
+
+func TestReadNPYRejectsBadShapes(t *testing.T) {
+	for _, shape := range []string{"(-1, 3)", "(3, -1)", "(-4,)", "(1048576, 1048576)", "(9223372036854775807, 2)"} {
+		t.Run(shape, func(t *testing.T) {
+			header := "{'descr': '<f8', 'fortran_order': False, 'shape': " + shape + ", }\n"
+			buf := append([]byte(nil), npyMagic...)
+			buf = append(buf, 1, 0)
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(len(header)))
+			buf = append(buf, header...)
+			if _, err := ReadNPY(bytes.NewReader(buf)); err == nil {
+				t.Errorf("ReadNPY accepted shape %s", shape)
+			}
+		})
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	m := NewMatrix(2, 3, []float64{1, -2, 3.5, 0, 1e-300, -7})
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "round trip", got, m)
+
+	buf.Reset()
+	if err := WriteNPY(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ReadNPY(&buf); err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "npy round trip", got, m)
+}
+
+func TestBinaryRoundTripEmpty(t *testing.T) {
+	for _, m := range []*Matrix{NewMatrix(0, 3, nil), NewMatrix(3, 0, nil), NewMatrix(0, 0, nil)} {
+		var buf bytes.Buffer
+		if err := WriteBinary(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadBinary(&buf)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", m.Rows, m.Cols, err)
+		}
+		if got.Rows != m.Rows || got.Cols != m.Cols || len(got.Data) != 0 {
+			t.Errorf("read a %dx%d matrix with %d values, want %dx%d", got.Rows, got.Cols, len(got.Data), m.Rows, m.Cols)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	m := NewMatrix(2, 3, []float64{1, -2, 0.1, 0, 1e-300, -7.123456789012345})
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	got, names, err := ReadCSV(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names != nil {
+		t.Errorf("names = %v without a header", names)
+	}
+	if !slices.Equal(got.Data, m.Data) || got.Rows != m.Rows || got.Cols != m.Cols {
+		t.Errorf("round trip = %dx%d %v, want %dx%d %v", got.Rows, got.Cols, got.Data, m.Rows, m.Cols, m.Data)
+	}
+
+	got, names, err = ReadCSV(strings.NewReader("a,b,c\n"+buf.String()), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"a", "b", "c"}) || !slices.Equal(got.Data, m.Data) {
+		t.Errorf("with header: names = %v, data = %v", names, got.Data)
+	}
+}
+
+func TestReadCSVRejects(t *testing.T) {
+	for _, input := range []string{"", "1,2\n3\n", "1,x\n"} {
+		if _, _, err := ReadCSV(strings.NewReader(input), false); err == nil {
+			t.Errorf("ReadCSV accepted %q", input)
+		}
+	}
+}
+
+func TestNPZRoundTrip(t *testing.T) {
+	arrays := map[string]*Matrix{
+		"weights": NewMatrix(2, 3, []float64{1, -2, 3.5, 0, 1e-300, -7}),
+		"bias":    NewMatrix(1, 2, []float64{0.5, -0.5}),
+	}
+	var buf bytes.Buffer
+	if err := WriteNPZ(&buf, arrays); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadNPZ(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(arrays) {
+		t.Fatalf("read %d arrays, want %d", len(got), len(arrays))
+	}
+	for name, want := range arrays {
+		if got[name] == nil {
+			t.Fatalf("array %q is missing", name)
+		}
+		assertMatrix(t, name, got[name], want)
+	}
+}
+
+func TestReadNPZCompressed(t *testing.T) {
+	want := NewMatrix(2, 2, []float64{1, 2, 3, 4})
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: "x.npy", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNPY(fw, want); err != nil {
+		t.Fatal(err)
+	}
+	// Files other than .npy arrays are skipped.
+	if fw, err = zw.Create("README"); err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("not an array"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadNPZ(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["x"] == nil {
+		t.Fatalf("arrays = %v, want only x", got)
+	}
+	assertMatrix(t, "x", got["x"], want)
+}
+
+// npyBytes builds a version 1.0 .npy file around raw data.
+func npyBytes(descr string, fortran bool, shape string, raw []byte) []byte {
+	order := "False"
+	if fortran {
+		order = "True"
+	}
+	header := "{'descr': '" + descr + "', 'fortran_order': " + order + ", 'shape': " + shape + ", }\n"
+	buf := append([]byte(nil), npyMagic...)
+	buf = append(buf, 1, 0)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(header)))
+	buf = append(buf, header...)
+	return append(buf, raw...)
+}
+
+func TestReadNPYFortranOrder(t *testing.T) {
+	// The 2x3 matrix [[1 2 3] [4 5 6]] stored column by column.
+	var raw []byte
+	for _, v := range []float64{1, 4, 2, 5, 3, 6} {
+		raw = binary.LittleEndian.AppendUint64(raw, math.Float64bits(v))
+	}
+	got, err := ReadNPY(bytes.NewReader(npyBytes("<f8", true, "(2, 3)", raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "fortran order", got, fromRows([][]float64{{1, 2, 3}, {4, 5, 6}}))
+
+	// A 1-D array is the same in either order.
+	got, err = ReadNPY(bytes.NewReader(npyBytes("<f8", true, "(6,)", raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "fortran order vector", got, fromRows([][]float64{{1, 4, 2, 5, 3, 6}}))
+}
+
+// appendNPY encodes v in the .npy dtype descr.
+func appendNPY(b []byte, descr string, v float64) []byte {
+	var order binary.AppendByteOrder = binary.LittleEndian
+	if descr[0] == '>' {
+		order = binary.BigEndian
+	}
+	switch descr[1:] {
+	case "f8":
+		return order.AppendUint64(b, math.Float64bits(v))
+	case "f4":
+		return order.AppendUint32(b, math.Float32bits(float32(v)))
+	case "i8", "u8":
+		return order.AppendUint64(b, uint64(int64(v)))
+	case "i4", "u4":
+		return order.AppendUint32(b, uint32(int64(v)))
+	case "i2", "u2":
+		return order.AppendUint16(b, uint16(int64(v)))
+	default:
+		return append(b, byte(int64(v)))
+	}
+}
+
+func TestReadNPYDtypes(t *testing.T) {
+	signed := []float64{1, -2, 3}
+	unsigned := []float64{1, 2, 255}
+	tests := []struct {
+		descr string
+		want  []float64
+	}{
+		{"<f8", signed},
+		{">f8", signed},
+		{"<f4", signed},
+		{">f4", signed},
+		{"<i8", signed},
+		{"<i4", signed},
+		{">i2", signed},
+		{"|i1", signed},
+		{"<u8", unsigned},
+		{"<u4", unsigned},
+		{">u2", unsigned},
+		{"|u1", unsigned},
+		{"|b1", []float64{1, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.descr, func(t *testing.T) {
+			var raw []byte
+			for _, v := range tt.want {
+				raw = appendNPY(raw, tt.descr, v)
+			}
+			got, err := ReadNPY(bytes.NewReader(npyBytes(tt.descr, false, "(3,)", raw)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Rows != 1 || !slices.Equal(got.Data, tt.want) {
+				t.Errorf("read %dx%d %v, want 1x3 %v", got.Rows, got.Cols, got.Data, tt.want)
+			}
+		})
+	}
+
+	for _, descr := range []string{"<c16", "<f2", "<U8", "f8"} {
+		if _, err := ReadNPY(bytes.NewReader(npyBytes(descr, false, "(1,)", make([]byte, 16)))); err == nil {
+			t.Errorf("ReadNPY accepted dtype %q", descr)
+		}
+	}
+}
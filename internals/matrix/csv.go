@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteCSV writes one line per row with full float64 precision.
+func WriteCSV(w io.Writer, m *Matrix) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, m.Cols)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			record[j] = strconv.FormatFloat(Get(i, j, m), 'g', -1, 64)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV parses numeric CSV into a matrix. When header is true the first
+// record is returned as column names instead of being parsed.
+func ReadCSV(r io.Reader, header bool) (*Matrix, []string, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	var names []string
+	var data []float64
+	rows, cols := 0, -1
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header && names == nil {
+			names = append([]string(nil), record...)
+			cols = len(record)
+			continue
+		}
+		if cols == -1 {
+			cols = len(record)
+		}
+		if len(record) != cols {
+			return nil, nil, fmt.Errorf("line %d: expected %d fields, got %d", line, cols, len(record))
+		}
+		for j, field := range record {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d, column %d: %w", line, j+1, err)
+			}
+			data = append(data, v)
+		}
+		rows++
+	}
+	if cols == -1 {
+		return nil, nil, errors.New("CSV input is empty")
+	}
+	return NewMatrix(rows, cols, data), names, nil
+}
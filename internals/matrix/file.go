@@ -0,0 +1,72 @@
+package matrix
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFile loads a matrix, picking the format from the file extension:
+// .csv, .npy, .npz (which must hold exactly one array) or .bin.
+func ReadFile(path string) (*Matrix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		m, _, err := ReadCSV(f, false)
+		return m, err
+	case ".npy":
+		return ReadNPY(f)
+	case ".npz":
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		arrays, err := ReadNPZ(f, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		if len(arrays) != 1 {
+			return nil, fmt.Errorf("%s holds %d arrays, use ReadNPZ to pick one", path, len(arrays))
+		}
+		for _, m := range arrays {
+			return m, nil
+		}
+	case ".bin":
+		return ReadBinary(f)
+	}
+	return nil, fmt.Errorf("unknown matrix file extension %q", filepath.Ext(path))
+}
+
+// WriteFile saves a matrix in the format matching the file extension. A .npz
+// file stores the matrix under the name "arr_0", as numpy.savez does.
+func WriteFile(path string, m *Matrix) error {
+	var write func(*os.File) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		write = func(f *os.File) error { return WriteCSV(f, m) }
+	case ".npy":
+		write = func(f *os.File) error { return WriteNPY(f, m) }
+	case ".npz":
+		write = func(f *os.File) error { return WriteNPZ(f, map[string]*Matrix{"arr_0": m}) }
+	case ".bin":
+		write = func(f *os.File) error { return WriteBinary(f, m) }
+	default:
+		return fmt.Errorf("unknown matrix file extension %q", filepath.Ext(path))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
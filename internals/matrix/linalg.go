@@ -0,0 +1,449 @@
+package matrix
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	ErrNotSquare           = errors.New("Matrix is not square")
+	ErrSingular            = errors.New("Matrix is singular")
+	ErrNotPositiveDefinite = errors.New("Matrix is not symmetric positive definite")
+)
+
+// svdMaxSweeps bounds the number of Jacobi sweeps in SVD. Convergence is
+// quadratic so well-conditioned inputs finish in well under ten.
+const svdMaxSweeps = 60
+
+func NewIdentity(n int) *Matrix {
+	out := NewMatrix(n, n, make([]float64, n*n))
+	for i := 0; i < n; i++ {
+		Set(i, i, 1, out)
+	}
+	return out
+}
+
+func Clone(m *Matrix) *Matrix {
+	data := make([]float64, len(m.Data))
+	copy(data, m.Data)
+	return NewMatrix(m.Rows, m.Cols, data)
+}
+
+// LU holds a factorisation P·A = L·U with partial pivoting. L (unit lower
+// triangular) and U are packed together in Factors; Pivot[i] is the row of A
+// that ended up in row i.
+type LU struct {
+	Factors *Matrix
+	Pivot   []int
+	Sign    float64
+}
+
+// LUDecompose factors a square matrix. If a zero pivot is met the partial
+// factorisation is returned together with ErrSingular.
+func LUDecompose(m *Matrix) (*LU, error) {
+	if m.Rows != m.Cols {
+		return nil, ErrNotSquare
+	}
+	n := m.Rows
+	a := Clone(m)
+	pivot := make([]int, n)
+	for i := range pivot {
+		pivot[i] = i
+	}
+	lu := &LU{Factors: a, Pivot: pivot, Sign: 1}
+	tol := singularTolerance(m)
+
+	var err error
+	for k := 0; k < n; k++ {
+		p := k
+		maxVal := math.Abs(Get(k, k, a))
+		for i := k + 1; i < n; i++ {
+			if v := math.Abs(Get(i, k, a)); v > maxVal {
+				maxVal = v
+				p = i
+			}
+		}
+		if maxVal <= tol {
+			err = ErrSingular
+			continue
+		}
+		if p != k {
+			swapRows(a, p, k)
+			pivot[p], pivot[k] = pivot[k], pivot[p]
+			lu.Sign = -lu.Sign
+		}
+		pivotVal := Get(k, k, a)
+		for i := k + 1; i < n; i++ {
+			f := Get(i, k, a) / pivotVal
+			Set(i, k, f, a)
+			for j := k + 1; j < n; j++ {
+				Set(i, j, Get(i, j, a)-f*Get(k, j, a), a)
+			}
+		}
+	}
+	return lu, err
+}
+
+func (lu *LU) L() *Matrix {
+	n := lu.Factors.Rows
+	out := NewIdentity(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			Set(i, j, Get(i, j, lu.Factors), out)
+		}
+	}
+	return out
+}
+
+func (lu *LU) U() *Matrix {
+	n := lu.Factors.Rows
+	out := NewMatrix(n, n, make([]float64, n*n))
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			Set(i, j, Get(i, j, lu.Factors), out)
+		}
+	}
+	return out
+}
+
+// P returns the permutation matrix such that P·A = L·U.
+func (lu *LU) P() *Matrix {
+	n := lu.Factors.Rows
+	out := NewMatrix(n, n, make([]float64, n*n))
+	for i, p := range lu.Pivot {
+		Set(i, p, 1, out)
+	}
+	return out
+}
+
+func (lu *LU) Determinant() float64 {
+	det := lu.Sign
+	for i := 0; i < lu.Factors.Rows; i++ {
+		det *= Get(i, i, lu.Factors)
+	}
+	return det
+}
+
+// Solve returns X with A·X = b for the factored A.
+func (lu *LU) Solve(b *Matrix) (*Matrix, error) {
+	n := lu.Factors.Rows
+	if b.Rows != n {
+		return nil, errors.New("Matrix dimensions are incompatable")
+	}
+	tol := singularTolerance(lu.Factors)
+	for i := 0; i < n; i++ {
+		if math.Abs(Get(i, i, lu.Factors)) <= tol {
+			return nil, ErrSingular
+		}
+	}
+
+	x := NewMatrix(n, b.Cols, make([]float64, n*b.Cols))
+	for i, p := range lu.Pivot {
+		copy(x.Data[i*b.Cols:(i+1)*b.Cols], b.Data[p*b.Cols:(p+1)*b.Cols])
+	}
+	for c := 0; c < b.Cols; c++ {
+		for i := 0; i < n; i++ {
+			sum := Get(i, c, x)
+			for k := 0; k < i; k++ {
+				sum -= Get(i, k, lu.Factors) * Get(k, c, x)
+			}
+			Set(i, c, sum, x)
+		}
+		for i := n - 1; i >= 0; i-- {
+			sum := Get(i, c, x)
+			for k := i + 1; k < n; k++ {
+				sum -= Get(i, k, lu.Factors) * Get(k, c, x)
+			}
+			Set(i, c, sum/Get(i, i, lu.Factors), x)
+		}
+	}
+	return x, nil
+}
+
+// QR holds a Householder factorisation A = Q·R with Q orthogonal (Rows×Rows)
+// and R upper triangular (Rows×Cols).
+type QR struct {
+	Q *Matrix
+	R *Matrix
+}
+
+func QRDecompose(m *Matrix) (*QR, error) {
+	rows, cols := m.Rows, m.Cols
+	r := Clone(m)
+	q := NewIdentity(rows)
+	v := make([]float64, rows)
+
+	steps := cols
+	if rows-1 < steps {
+		steps = rows - 1
+	}
+	for k := 0; k < steps; k++ {
+		norm := 0.0
+		for i := k; i < rows; i++ {
+			norm = math.Hypot(norm, Get(i, k, r))
+		}
+		if norm == 0 {
+			continue
+		}
+		alpha := -norm
+		if Get(k, k, r) < 0 {
+			alpha = norm
+		}
+		vNorm := 0.0
+		for i := k; i < rows; i++ {
+			v[i] = Get(i, k, r)
+			if i == k {
+				v[i] -= alpha
+			}
+			vNorm += v[i] * v[i]
+		}
+		if vNorm == 0 {
+			continue
+		}
+
+		// R = H·R and Q = Q·H with H = I - 2vvᵀ/(vᵀv).
+		for j := 0; j < cols; j++ {
+			dot := 0.0
+			for i := k; i < rows; i++ {
+				dot += v[i] * Get(i, j, r)
+			}
+			f := 2 * dot / vNorm
+			for i := k; i < rows; i++ {
+				Set(i, j, Get(i, j, r)-f*v[i], r)
+			}
+		}
+		for i := 0; i < rows; i++ {
+			dot := 0.0
+			for j := k; j < rows; j++ {
+				dot += Get(i, j, q) * v[j]
+			}
+			f := 2 * dot / vNorm
+			for j := k; j < rows; j++ {
+				Set(i, j, Get(i, j, q)-f*v[j], q)
+			}
+		}
+		for i := k + 1; i < rows; i++ {
+			Set(i, k, 0, r)
+		}
+	}
+	return &QR{Q: q, R: r}, nil
+}
+
+// Cholesky returns the lower triangular L with A = L·Lᵀ.
+func Cholesky(m *Matrix) (*Matrix, error) {
+	if m.Rows != m.Cols {
+		return nil, ErrNotSquare
+	}
+	n := m.Rows
+	tol := singularTolerance(m)
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			if math.Abs(Get(i, j, m)-Get(j, i, m)) > tol {
+				return nil, ErrNotPositiveDefinite
+			}
+		}
+	}
+
+	l := NewMatrix(n, n, make([]float64, n*n))
+	for j := 0; j < n; j++ {
+		sum := Get(j, j, m)
+		for k := 0; k < j; k++ {
+			sum -= Get(j, k, l) * Get(j, k, l)
+		}
+		if sum <= 0 {
+			return nil, ErrNotPositiveDefinite
+		}
+		d := math.Sqrt(sum)
+		Set(j, j, d, l)
+		for i := j + 1; i < n; i++ {
+			s := Get(i, j, m)
+			for k := 0; k < j; k++ {
+				s -= Get(i, k, l) * Get(j, k, l)
+			}
+			Set(i, j, s/d, l)
+		}
+	}
+	return l, nil
+}
+
+// SVDResult holds the thin decomposition A = U·diag(S)·Vᵀ, with the singular
+// values in S sorted in descending order. For an m×n input U is m×k and V is
+// n×k where k = min(m, n).
+type SVDResult struct {
+	U *Matrix
+	S []float64
+	V *Matrix
+}
+
+// SVD uses one-sided Jacobi rotations, which is slow for large inputs but
+// accurate and simple enough for initialisation and PCA on feature columns.
+func SVD(m *Matrix) (*SVDResult, error) {
+	if m.Rows < m.Cols {
+		res, err := SVD(Transpose(m))
+		if err != nil {
+			return nil, err
+		}
+		return &SVDResult{U: res.V, S: res.S, V: res.U}, nil
+	}
+
+	rows, cols := m.Rows, m.Cols
+	a := Clone(m)
+	v := NewIdentity(cols)
+
+	converged := false
+	for sweep := 0; sweep < svdMaxSweeps && !converged; sweep++ {
+		converged = true
+		for p := 0; p < cols-1; p++ {
+			for q := p + 1; q < cols; q++ {
+				alpha, beta, gamma := 0.0, 0.0, 0.0
+				for i := 0; i < rows; i++ {
+					ap, aq := Get(i, p, a), Get(i, q, a)
+					alpha += ap * ap
+					beta += aq * aq
+					gamma += ap * aq
+				}
+				if gamma == 0 || math.Abs(gamma) <= 1e-15*math.Sqrt(alpha*beta) {
+					continue
+				}
+				converged = false
+
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				s := c * t
+				rotateColumns(a, p, q, c, s)
+				rotateColumns(v, p, q, c, s)
+			}
+		}
+	}
+	if !converged {
+		return nil, errors.New("SVD did not converge")
+	}
+
+	sigma := make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		norm := 0.0
+		for i := 0; i < rows; i++ {
+			norm = math.Hypot(norm, Get(i, j, a))
+		}
+		sigma[j] = norm
+	}
+	order := make([]int, cols)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sigma[order[i]] > sigma[order[j]] })
+
+	u := NewMatrix(rows, cols, make([]float64, rows*cols))
+	vSorted := NewMatrix(cols, cols, make([]float64, cols*cols))
+	s := make([]float64, cols)
+	for k, j := range order {
+		s[k] = sigma[j]
+		for i := 0; i < rows; i++ {
+			if sigma[j] != 0 {
+				Set(i, k, Get(i, j, a)/sigma[j], u)
+			}
+		}
+		for i := 0; i < cols; i++ {
+			Set(i, k, Get(i, j, v), vSorted)
+		}
+	}
+	return &SVDResult{U: u, S: s, V: vSorted}, nil
+}
+
+func Determinant(m *Matrix) (float64, error) {
+	lu, err := LUDecompose(m)
+	if errors.Is(err, ErrSingular) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return lu.Determinant(), nil
+}
+
+func Inverse(m *Matrix) (*Matrix, error) {
+	lu, err := LUDecompose(m)
+	if err != nil {
+		return nil, err
+	}
+	return lu.Solve(NewIdentity(m.Rows))
+}
+
+// Solve returns X with A·X = b. Square systems are solved through LU; for
+// overdetermined systems (more rows than columns) the least-squares solution
+// is computed through QR.
+func Solve(a, b *Matrix) (*Matrix, error) {
+	if a.Rows != b.Rows {
+		return nil, errors.New("Matrix dimensions are incompatable")
+	}
+	if a.Rows == a.Cols {
+		lu, err := LUDecompose(a)
+		if err != nil {
+			return nil, err
+		}
+		return lu.Solve(b)
+	}
+	if a.Rows < a.Cols {
+		return nil, errors.New("System is underdetermined")
+	}
+
+	qr, err := QRDecompose(a)
+	if err != nil {
+		return nil, err
+	}
+	n := a.Cols
+	qtb := NewMatrix(a.Rows, b.Cols, make([]float64, a.Rows*b.Cols))
+	if err := DotProduct(Transpose(qr.Q), b, qtb); err != nil {
+		return nil, err
+	}
+	tol := singularTolerance(a)
+	x := NewMatrix(n, b.Cols, make([]float64, n*b.Cols))
+	for c := 0; c < b.Cols; c++ {
+		for i := n - 1; i >= 0; i-- {
+			d := Get(i, i, qr.R)
+			if math.Abs(d) <= tol {
+				return nil, ErrSingular
+			}
+			sum := Get(i, c, qtb)
+			for k := i + 1; k < n; k++ {
+				sum -= Get(i, k, qr.R) * Get(k, c, x)
+			}
+			Set(i, c, sum/d, x)
+		}
+	}
+	return x, nil
+}
+
+// singularTolerance scales machine epsilon by the size and magnitude of m so
+// that pivots of rounding-error size are treated as zero.
+func singularTolerance(m *Matrix) float64 {
+	maxAbs := 0.0
+	for _, v := range m.Data {
+		if a := math.Abs(v); a > maxAbs {
+			maxAbs = a
+		}
+	}
+	n := m.Rows
+	if m.Cols > n {
+		n = m.Cols
+	}
+	return float64(n) * maxAbs * 1e-14
+}
+
+func swapRows(m *Matrix, i, j int) {
+	ri := m.Data[i*m.Cols : (i+1)*m.Cols]
+	rj := m.Data[j*m.Cols : (j+1)*m.Cols]
+	for k := range ri {
+		ri[k], rj[k] = rj[k], ri[k]
+	}
+}
+
+func rotateColumns(m *Matrix, p, q int, c, s float64) {
+	for i := 0; i < m.Rows; i++ {
+		mp, mq := Get(i, p, m), Get(i, q, m)
+		Set(i, p, c*mp-s*mq, m)
+		Set(i, q, s*mp+c*mq, m)
+	}
+}
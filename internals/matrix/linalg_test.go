@@ -0,0 +1,261 @@
+package matrix
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+const linalgTol = 1e-9
+
+func fromRows(rows [][]float64) *Matrix {
+	m := NewMatrix(len(rows), len(rows[0]), make([]float64, 0, len(rows)*len(rows[0])))
+	for _, row := range rows {
+		m.Data = append(m.Data, row...)
+	}
+	return m
+}
+
+func mul(t *testing.T, a, b *Matrix) *Matrix {
+	t.Helper()
+	out := NewMatrix(a.Rows, b.Cols, make([]float64, a.Rows*b.Cols))
+	if err := DotProduct(a, b, out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func assertMatrix(t *testing.T, name string, got, want *Matrix) {
+	t.Helper()
+	if got.Rows != want.Rows || got.Cols != want.Cols {
+		t.Fatalf("%s is %dx%d, want %dx%d", name, got.Rows, got.Cols, want.Rows, want.Cols)
+	}
+	for i := range want.Data {
+		if math.Abs(got.Data[i]-want.Data[i]) > linalgTol {
+			t.Fatalf("%s = %v, want %v", name, got.Data, want.Data)
+		}
+	}
+}
+
+func TestLUDecompose(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		det  float64
+	}{
+		{"pivoting 2x2", [][]float64{{4, 3}, {6, 3}}, -6},
+		{"3x3", [][]float64{{2, 1, 1}, {4, -6, 0}, {-2, 7, 2}}, -16},
+		{"zero leading pivot", [][]float64{{0, 1}, {1, 0}}, -1},
+		{"identity", [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := fromRows(tt.a)
+			lu, err := LUDecompose(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertMatrix(t, "L·U", mul(t, lu.L(), lu.U()), mul(t, lu.P(), a))
+			if got := lu.Determinant(); math.Abs(got-tt.det) > linalgTol {
+				t.Errorf("LU determinant = %v, want %v", got, tt.det)
+			}
+			got, err := Determinant(a)
+			if err != nil || math.Abs(got-tt.det) > linalgTol {
+				t.Errorf("Determinant = %v, %v, want %v", got, err, tt.det)
+			}
+		})
+	}
+}
+
+func TestLUPivotsLargestEntry(t *testing.T) {
+	lu, err := LUDecompose(fromRows([][]float64{{4, 3}, {6, 3}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "L", lu.L(), fromRows([][]float64{{1, 0}, {2.0 / 3, 1}}))
+	assertMatrix(t, "U", lu.U(), fromRows([][]float64{{6, 3}, {0, 1}}))
+}
+
+func TestInverse(t *testing.T) {
+	a := fromRows([][]float64{{4, 7}, {2, 6}})
+	inv, err := Inverse(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "inverse", inv, fromRows([][]float64{{0.6, -0.7}, {-0.2, 0.4}}))
+	assertMatrix(t, "inverse·A", mul(t, inv, a), NewIdentity(2))
+
+	b := fromRows([][]float64{{2, 1, 1}, {4, -6, 0}, {-2, 7, 2}})
+	inv, err = Inverse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "inverse·A", mul(t, inv, b), NewIdentity(3))
+	assertMatrix(t, "A·inverse", mul(t, b, inv), NewIdentity(3))
+}
+
+func TestSolve(t *testing.T) {
+	a := fromRows([][]float64{{2, 1, 1}, {4, -6, 0}, {-2, 7, 2}})
+	b := fromRows([][]float64{{7, 1}, {-8, 4}, {18, -4}})
+	x, err := Solve(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The columns of b are A·(1, 2, 3) and A·(1, 0, -1).
+	assertMatrix(t, "x", x, fromRows([][]float64{{1, 1}, {2, 0}, {3, -1}}))
+}
+
+func TestSolveLeastSquares(t *testing.T) {
+	// Fit y = c0 + c1·x to (0, 1), (1, 2), (2, 2). The normal equations
+	// [3 3; 3 5]·c = [5; 6] give c = (7/6, 1/2).
+	a := fromRows([][]float64{{1, 0}, {1, 1}, {1, 2}})
+	y := fromRows([][]float64{{1}, {2}, {2}})
+	c, err := Solve(a, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "c", c, fromRows([][]float64{{7.0 / 6}, {0.5}}))
+}
+
+func TestQRDecompose(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      [][]float64
+		rDiags []float64
+	}{
+		{"square", [][]float64{{12, -51, 4}, {6, 167, -68}, {-4, 24, -41}}, []float64{14, 175, 35}},
+		{"tall", [][]float64{{3, 0}, {4, 5}, {0, 0}}, []float64{5, 3}},
+		{"wide", [][]float64{{1, 2, 3}, {4, 5, 6}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := fromRows(tt.a)
+			qr, err := QRDecompose(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertMatrix(t, "Q·R", mul(t, qr.Q, qr.R), a)
+			assertMatrix(t, "Qᵀ·Q", mul(t, Transpose(qr.Q), qr.Q), NewIdentity(a.Rows))
+			for i := 0; i < qr.R.Rows; i++ {
+				for j := 0; j < min(i, qr.R.Cols); j++ {
+					if Get(i, j, qr.R) != 0 {
+						t.Fatalf("R is not upper triangular: %v", qr.R.Data)
+					}
+				}
+			}
+			for i, want := range tt.rDiags {
+				if got := math.Abs(Get(i, i, qr.R)); math.Abs(got-want) > linalgTol {
+					t.Errorf("|R[%d,%d]| = %v, want %v", i, i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	a := fromRows([][]float64{{4, 12, -16}, {12, 37, -43}, {-16, -43, 98}})
+	l, err := Cholesky(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatrix(t, "L", l, fromRows([][]float64{{2, 0, 0}, {6, 1, 0}, {-8, 5, 3}}))
+	assertMatrix(t, "L·Lᵀ", mul(t, l, Transpose(l)), a)
+}
+
+func TestSVD(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		s    []float64
+	}{
+		// AᵀA = [25 20; 20 25] has eigenvalues 45 and 5.
+		{"square", [][]float64{{3, 0}, {4, 5}}, []float64{3 * math.Sqrt(5), math.Sqrt(5)}},
+		{"wide", [][]float64{{3, 2, 2}, {2, 3, -2}}, []float64{5, 3}},
+		{"tall", [][]float64{{1, 0}, {0, 2}, {0, 0}}, []float64{2, 1}},
+		{"rank deficient", [][]float64{{1, 2}, {2, 4}}, []float64{5, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := fromRows(tt.a)
+			res, err := SVD(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.s {
+				if math.Abs(res.S[i]-want) > linalgTol {
+					t.Fatalf("S = %v, want %v", res.S, tt.s)
+				}
+			}
+			k := len(res.S)
+			us := Clone(res.U)
+			for i := 0; i < us.Rows; i++ {
+				for j := 0; j < k; j++ {
+					Set(i, j, Get(i, j, us)*res.S[j], us)
+				}
+			}
+			assertMatrix(t, "U·S·Vᵀ", mul(t, us, Transpose(res.V)), a)
+			assertMatrix(t, "Vᵀ·V", mul(t, Transpose(res.V), res.V), NewIdentity(k))
+		})
+	}
+}
+
+func TestSingular(t *testing.T) {
+	a := fromRows([][]float64{{1, 2}, {2, 4}})
+	if _, err := LUDecompose(a); !errors.Is(err, ErrSingular) {
+		t.Errorf("LUDecompose error = %v, want ErrSingular", err)
+	}
+	if _, err := Inverse(a); !errors.Is(err, ErrSingular) {
+		t.Errorf("Inverse error = %v, want ErrSingular", err)
+	}
+	if _, err := Solve(a, fromRows([][]float64{{1}, {2}})); !errors.Is(err, ErrSingular) {
+		t.Errorf("Solve error = %v, want ErrSingular", err)
+	}
+	if det, err := Determinant(a); err != nil || det != 0 {
+		t.Errorf("Determinant = %v, %v, want 0", det, err)
+	}
+	// Dependent columns make the least-squares problem singular too.
+	tall := fromRows([][]float64{{1, 2}, {2, 4}, {3, 6}})
+	if _, err := Solve(tall, fromRows([][]float64{{1}, {2}, {3}})); !errors.Is(err, ErrSingular) {
+		t.Errorf("least-squares Solve error = %v, want ErrSingular", err)
+	}
+}
+
+func TestCholeskyNotPositiveDefinite(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+	}{
+		{"indefinite", [][]float64{{1, 2}, {2, 1}}},
+		{"not symmetric", [][]float64{{1, 2}, {0, 1}}},
+		{"zero", [][]float64{{0, 0}, {0, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Cholesky(fromRows(tt.a)); !errors.Is(err, ErrNotPositiveDefinite) {
+				t.Errorf("Cholesky error = %v, want ErrNotPositiveDefinite", err)
+			}
+		})
+	}
+}
+
+func TestNonSquare(t *testing.T) {
+	a := fromRows([][]float64{{1, 2, 3}, {4, 5, 6}})
+	if _, err := LUDecompose(a); !errors.Is(err, ErrNotSquare) {
+		t.Errorf("LUDecompose error = %v, want ErrNotSquare", err)
+	}
+	if _, err := Cholesky(a); !errors.Is(err, ErrNotSquare) {
+		t.Errorf("Cholesky error = %v, want ErrNotSquare", err)
+	}
+	if _, err := Inverse(a); !errors.Is(err, ErrNotSquare) {
+		t.Errorf("Inverse error = %v, want ErrNotSquare", err)
+	}
+	if _, err := Determinant(a); !errors.Is(err, ErrNotSquare) {
+		t.Errorf("Determinant error = %v, want ErrNotSquare", err)
+	}
+	if _, err := Solve(a, fromRows([][]float64{{1}, {2}})); err == nil {
+		t.Error("Solve of an underdetermined system succeeded")
+	}
+	if _, err := Solve(fromRows([][]float64{{1, 0}, {0, 1}}), fromRows([][]float64{{1}, {2}, {3}})); err == nil {
+		t.Error("Solve with mismatched rows succeeded")
+	}
+}
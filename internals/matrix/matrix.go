@@ -1,13 +1,6 @@
 package matrix
 
-import (
-	"errors"
-	"runtime"
-
-	"github.com/whyisemerald/neural_network/internals/routines"
-)
-
-const PARALLEL_THRESHOLD = 422500
+import "errors"
 
 type Matrix struct {
 	Rows int
@@ -51,72 +44,56 @@ func DotProduct(m1, m2, out *Matrix) error {
 	if c1 != r2 {
 		return errors.New("Matrix dimensions are incompatable")
 	}
-	if len(m1.Data) > PARALLEL_THRESHOLD {
-		pool := routines.GlobalPool
-		numWorkers := runtime.NumCPU()
-		chunkSize := (r1 + numWorkers - 1) / numWorkers
-
-		for i := 0; i < r1; i += chunkSize {
-			startRow := i
-			endRow := i + chunkSize
-			if endRow > r1 {
-				endRow = r1
-			}
-			pool.AddTask(func() {
-				for row := startRow; row < endRow; row++ {
-					for j := 0; j < c2; j++ {
-						sum := 0.0
-						for k := 0; k < c1; k++ {
-							sum += Get(row, k, m1) * Get(k, j, m2)
-						}
-						Set(row, j, sum, out)
-					}
-				}
-			})
-		}
-		pool.WaitAll()
-	} else {
-		for i := 0; i < r1; i++ {
-			for j := 0; j < c2; j++ {
-				sum := 0.0
-				for k := 0; k < c1; k++ {
-					sum += Get(i, k, m1) * Get(k, j, m2)
-				}
-				Set(i, j, sum, out)
+	work := r1 * c1 * c2
+
+	// Split over whichever output dimension is larger so that a single wide
+	// row vector (the batch size 1 case) still parallelizes.
+	if r1 >= c2 {
+		return parallelize(OpDotProduct, work, r1, func(startRow, endRow int) {
+			dotProductBlock(m1, m2, out, startRow, endRow, 0, c2)
+		})
+	}
+	return parallelize(OpDotProduct, work, c2, func(startCol, endCol int) {
+		dotProductBlock(m1, m2, out, 0, r1, startCol, endCol)
+	})
+}
+
+// dotProductBlock computes out[startRow:endRow, startCol:endCol] of m1 × m2.
+func dotProductBlock(m1, m2, out *Matrix, startRow, endRow, startCol, endCol int) {
+	c1 := m1.Cols
+	for row := startRow; row < endRow; row++ {
+		for j := startCol; j < endCol; j++ {
+			sum := 0.0
+			for k := 0; k < c1; k++ {
+				sum += Get(row, k, m1) * Get(k, j, m2)
 			}
+			Set(row, j, sum, out)
 		}
 	}
-	return nil
 }
 
 func Transpose(m *Matrix) *Matrix {
 	out := NewMatrix(m.Cols, m.Rows, make([]float64, m.Rows*m.Cols))
-	if len(m.Data) > PARALLEL_THRESHOLD {
-		pool := routines.GlobalPool
-		numWorkers := runtime.NumCPU()
-		chunkSize := (m.Rows + numWorkers - 1) / numWorkers
-
-		for i := 0; i < m.Rows; i += chunkSize {
-			startRow := i
-			endRow := i + chunkSize
-			if endRow > m.Rows {
-				endRow = m.Rows
-			}
-			pool.AddTask(func() {
-				for row := startRow; row < endRow; row++ {
-					for j := 0; j < m.Cols; j++ {
-						Set(j, row, Get(row, j, m), out)
-					}
+	var err error
+	if m.Rows >= m.Cols {
+		err = parallelize(OpTranspose, len(m.Data), m.Rows, func(startRow, endRow int) {
+			for row := startRow; row < endRow; row++ {
+				for j := 0; j < m.Cols; j++ {
+					Set(j, row, Get(row, j, m), out)
 				}
-			})
-		}
-		pool.WaitAll()
+			}
+		})
 	} else {
-		for i := 0; i < m.Rows; i++ {
-			for j := 0; j < m.Cols; j++ {
-				Set(j, i, Get(i, j, m), out)
+		err = parallelize(OpTranspose, len(m.Data), m.Cols, func(startCol, endCol int) {
+			for i := 0; i < m.Rows; i++ {
+				for col := startCol; col < endCol; col++ {
+					Set(col, i, Get(i, col, m), out)
+				}
 			}
-		}
+		})
+	}
+	if err != nil {
+		panic(err)
 	}
 	return out
 }
@@ -128,32 +105,13 @@ func MultiplyElementWise(m1, m2, out *Matrix) error {
 }
 
 func MultiplyScalar(m *Matrix, scalar float64, out *Matrix) {
-	if len(m.Data) > PARALLEL_THRESHOLD {
-		pool := routines.GlobalPool
-		numWorkers := runtime.NumCPU()
-		chunkSize := (m.Rows + numWorkers - 1) / numWorkers
-
-		for i := 0; i < m.Rows; i += chunkSize {
-			startRow := i
-			endRow := i + chunkSize
-			if endRow > m.Rows {
-				endRow = m.Rows
-			}
-			pool.AddTask(func() {
-				for row := startRow; row < endRow; row++ {
-					for j := 0; j < m.Cols; j++ {
-						Set(row, j, Get(row, j, m)*scalar, out)
-					}
-				}
-			})
-		}
-		pool.WaitAll()
-	} else {
-		for i := 0; i < m.Rows; i++ {
-			for j := 0; j < m.Cols; j++ {
-				Set(i, j, Get(i, j, m)*scalar, out)
-			}
+	err := parallelize(OpScalar, len(m.Data), len(m.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			out.Data[i] = m.Data[i] * scalar
 		}
+	})
+	if err != nil {
+		panic(err)
 	}
 }
 
@@ -161,61 +119,37 @@ func elementWiseOp(m1, m2, out *Matrix, op func(float64, float64) float64) error
 	if m1.Rows != m2.Rows || m1.Cols != m2.Cols {
 		return errors.New("Matrix dimensions do not match")
 	}
-	if len(m1.Data) > PARALLEL_THRESHOLD {
-		pool := routines.GlobalPool
-		numWorkers := runtime.NumCPU()
-		chunkSize := (m1.Rows + numWorkers - 1) / numWorkers
-
-		for i := 0; i < m1.Rows; i += chunkSize {
-			startRow := i
-			endRow := i + chunkSize
-			if endRow > m1.Rows {
-				endRow = m1.Rows
-			}
-			pool.AddTask(func() {
-				for row := startRow; row < endRow; row++ {
-					for j := 0; j < m1.Cols; j++ {
-						Set(row, j, op(Get(row, j, m1), Get(row, j, m2)), out)
-					}
-				}
-			})
-		}
-		pool.WaitAll()
-	} else {
-		for i := 0; i < m1.Rows; i++ {
-			for j := 0; j < m1.Cols; j++ {
-				Set(i, j, op(Get(i, j, m1), Get(i, j, m2)), out)
-			}
+	return parallelize(OpElementWise, len(m1.Data), len(m1.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			out.Data[i] = op(m1.Data[i], m2.Data[i])
 		}
-	}
-	return nil
+	})
 }
+
+// ApplyFunction sets out to fn applied to every element of m. If fn panics on
+// a pool worker the panic is re-raised in the caller as a
+// *routines.PanicError.
 func ApplyFunction(m *Matrix, fn func(float64) float64, out *Matrix) {
-	if len(m.Data) > PARALLEL_THRESHOLD {
-		pool := routines.GlobalPool
-		numWorkers := runtime.NumCPU()
-		chunkSize := (m.Rows + numWorkers - 1) / numWorkers
-
-		for i := 0; i < m.Rows; i += chunkSize {
-			startRow := i
-			endRow := i + chunkSize
-			if endRow > m.Rows {
-				endRow = m.Rows
-			}
-			pool.AddTask(func() {
-				for row := startRow; row < endRow; row++ {
-					for j := 0; j < m.Cols; j++ {
-						Set(row, j, fn(Get(row, j, m)), out)
-					}
-				}
-			})
-		}
-		pool.WaitAll()
-	} else {
-		for i := 0; i < m.Rows; i++ {
-			for j := 0; j < m.Cols; j++ {
-				Set(i, j, fn(Get(i, j, m)), out)
-			}
+	err := parallelize(OpApply, len(m.Data), len(m.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			out.Data[i] = fn(m.Data[i])
 		}
+	})
+	if err != nil {
+		panic(err)
 	}
-}
\ No newline at end of file
+}
+
+// AddRowVector adds the 1×Cols matrix row to every row of m. This is the
+// bias add for a batch of layer outputs.
+func AddRowVector(m, row, out *Matrix) error {
+	if row.Rows != 1 || row.Cols != m.Cols {
+		return errors.New("Matrix dimensions do not match")
+	}
+	cols := m.Cols
+	return parallelize(OpElementWise, len(m.Data), len(m.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			out.Data[i] = m.Data[i] + row.Data[i%cols]
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package matrix
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var npyMagic = []byte("\x93NUMPY")
+
+// WriteNPY writes m as a 2-D little-endian float64 array in NumPy's .npy
+// format (version 1.0).
+func WriteNPY(w io.Writer, m *Matrix) error {
+	dict := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", m.Rows, m.Cols)
+	// magic + version + header length, then the dict padded with spaces so
+	// the data starts on a 64 byte boundary, terminated by a newline.
+	prefix := len(npyMagic) + 2 + 2
+	total := prefix + len(dict) + 1
+	padding := (64 - total%64) % 64
+	header := dict + strings.Repeat(" ", padding) + "\n"
+
+	buf := make([]byte, 0, prefix+len(header))
+	buf = append(buf, npyMagic...)
+	buf = append(buf, 1, 0)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(header)))
+	buf = append(buf, header...)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, 8*len(m.Data))
+	for _, v := range m.Data {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// ReadNPY reads a .npy array of up to two dimensions. One dimensional arrays
+// become a single row and scalars a 1×1 matrix. Floating point, signed and
+// unsigned integer and boolean dtypes of either byte order are converted to
+// float64.
+func ReadNPY(r io.Reader) (*Matrix, error) {
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix[:len(npyMagic)], npyMagic) {
+		return nil, errors.New("Not a .npy file")
+	}
+
+	var headerLen int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		var n [2]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return nil, err
+		}
+		headerLen = int(binary.LittleEndian.Uint16(n[:]))
+	case 2, 3:
+		var n [4]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return nil, err
+		}
+		headerLen = int(binary.LittleEndian.Uint32(n[:]))
+	default:
+		return nil, fmt.Errorf("unsupported .npy version %d", major)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	descr, fortran, shape, err := parseNPYHeader(string(header))
+	if err != nil {
+		return nil, err
+	}
+	decode, size, order, err := npyDecoder(descr)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, cols := 1, 1
+	switch len(shape) {
+	case 0:
+	case 1:
+		cols = shape[0]
+	case 2:
+		rows, cols = shape[0], shape[1]
+	default:
+		return nil, fmt.Errorf("cannot load a %d-dimensional array into a matrix", len(shape))
+	}
+	if rows != 0 && cols > maxReadElements/rows {
+		return nil, fmt.Errorf(".npy array of shape %v is too large", shape)
+	}
+
+	raw := make([]byte, rows*cols*size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = decode(raw[i*size:(i+1)*size], order)
+	}
+	m := NewMatrix(rows, cols, data)
+	if fortran && len(shape) == 2 {
+		// Column-major data read as row-major is the transpose.
+		m = Transpose(NewMatrix(cols, rows, data))
+	}
+	return m, nil
+}
+
+// parseNPYHeader extracts the three keys of the Python dict literal in a
+// .npy header. It only understands the subset NumPy itself writes.
+func parseNPYHeader(header string) (string, bool, []int, error) {
+	value := func(key string) (string, error) {
+		i := strings.Index(header, "'"+key+"'")
+		if i < 0 {
+			return "", fmt.Errorf(".npy header is missing %q", key)
+		}
+		rest := strings.TrimSpace(header[i+len(key)+2:])
+		if !strings.HasPrefix(rest, ":") {
+			return "", fmt.Errorf(".npy header has no value for %q", key)
+		}
+		return strings.TrimSpace(rest[1:]), nil
+	}
+
+	descrValue, err := value("descr")
+	if err != nil {
+		return "", false, nil, err
+	}
+	if len(descrValue) < 2 || descrValue[0] != '\'' {
+		return "", false, nil, errors.New("structured .npy dtypes are not supported")
+	}
+	end := strings.IndexByte(descrValue[1:], '\'')
+	if end < 0 {
+		return "", false, nil, errors.New("malformed .npy descr")
+	}
+	descr := descrValue[1 : end+1]
+
+	fortranValue, err := value("fortran_order")
+	if err != nil {
+		return "", false, nil, err
+	}
+	fortran := strings.HasPrefix(fortranValue, "True")
+
+	shapeValue, err := value("shape")
+	if err != nil {
+		return "", false, nil, err
+	}
+	if !strings.HasPrefix(shapeValue, "(") {
+		return "", false, nil, errors.New("malformed .npy shape")
+	}
+	closing := strings.IndexByte(shapeValue, ')')
+	if closing < 0 {
+		return "", false, nil, errors.New("malformed .npy shape")
+	}
+	var shape []int
+	for _, part := range strings.Split(shapeValue[1:closing], ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		dim, err := strconv.Atoi(strings.TrimSuffix(part, "L"))
+		if err != nil {
+			return "", false, nil, fmt.Errorf("malformed .npy shape: %w", err)
+		}
+		if dim < 0 {
+			return "", false, nil, fmt.Errorf("negative .npy dimension %d", dim)
+		}
+		shape = append(shape, dim)
+	}
+	return descr, fortran, shape, nil
+}
+
+// npyDecoder returns a function converting one element of the given dtype
+// to float64, the element size and its byte order.
+func npyDecoder(descr string) (func([]byte, binary.ByteOrder) float64, int, binary.ByteOrder, error) {
+	if len(descr) < 3 {
+		return nil, 0, nil, fmt.Errorf("unsupported .npy dtype %q", descr)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if descr[0] == '>' {
+		order = binary.BigEndian
+	}
+	kind := descr[1]
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("unsupported .npy dtype %q", descr)
+	}
+
+	var decode func([]byte, binary.ByteOrder) float64
+	switch {
+	case kind == 'f' && size == 8:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return math.Float64frombits(o.Uint64(b)) }
+	case kind == 'f' && size == 4:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(math.Float32frombits(o.Uint32(b))) }
+	case kind == 'i' && size == 8:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(int64(o.Uint64(b))) }
+	case kind == 'i' && size == 4:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(int32(o.Uint32(b))) }
+	case kind == 'i' && size == 2:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(int16(o.Uint16(b))) }
+	case kind == 'i' && size == 1:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(int8(b[0])) }
+	case kind == 'u' && size == 8:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(o.Uint64(b)) }
+	case kind == 'u' && size == 4:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(o.Uint32(b)) }
+	case kind == 'u' && size == 2:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(o.Uint16(b)) }
+	case (kind == 'u' || kind == 'b') && size == 1:
+		decode = func(b []byte, o binary.ByteOrder) float64 { return float64(b[0]) }
+	default:
+		return nil, 0, nil, fmt.Errorf("unsupported .npy dtype %q", descr)
+	}
+	return decode, size, order, nil
+}
+
+// WriteNPZ writes the named matrices as an uncompressed .npz archive, the
+// format produced by numpy.savez.
+func WriteNPZ(w io.Writer, arrays map[string]*Matrix) error {
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := WriteNPY(fw, arrays[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ReadNPZ reads every array in a .npz archive, compressed or not, keyed by
+// its name without the .npy suffix.
+func ReadNPZ(r io.ReaderAt, size int64) (map[string]*Matrix, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	arrays := make(map[string]*Matrix, len(zr.File))
+	for _, f := range zr.File {
+		if path.Ext(f.Name) != ".npy" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		m, err := ReadNPY(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = m
+	}
+	return arrays, nil
+}
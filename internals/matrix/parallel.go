@@ -0,0 +1,246 @@
+package matrix
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+// Op identifies a matrix operation for the purpose of choosing between the
+// serial and the parallel implementation.
+type Op int
+
+const (
+	OpDotProduct Op = iota
+	OpSparseDotProduct
+	OpTranspose
+	OpElementWise
+	OpScalar
+	OpApply
+	numOps
+)
+
+var opNames = [numOps]string{"DotProduct", "SparseDotProduct", "Transpose", "ElementWise", "Scalar", "Apply"}
+
+func (op Op) String() string {
+	if op < 0 || op >= numOps {
+		return "Op(?)"
+	}
+	return opNames[op]
+}
+
+// Ops lists every operation with a parallel threshold.
+func Ops() []Op {
+	ops := make([]Op, numOps)
+	for i := range ops {
+		ops[i] = Op(i)
+	}
+	return ops
+}
+
+// defaultThresholds are in estimated floating point operations (multiply-adds
+// for products, element visits otherwise). They were measured on an 8 core
+// machine and are only a starting point; see Calibrate.
+var defaultThresholds = [numOps]int64{
+	OpDotProduct:       1 << 17,
+	OpSparseDotProduct: 1 << 17,
+	OpTranspose:        1 << 18,
+	OpElementWise:      1 << 18,
+	OpScalar:           1 << 18,
+	OpApply:            1 << 15,
+}
+
+var thresholds [numOps]atomic.Int64
+
+// PARALLEL_THRESHOLD was the element count above which every operation ran
+// in parallel. Nothing reads it any more, so it has no effect on when
+// operations run in parallel, and code comparing sizes against it no longer
+// matches what the package does.
+//
+// Deprecated: thresholds are per operation and in estimated work; use
+// ParallelThreshold and SetParallelThreshold. PARALLEL_THRESHOLD will be
+// removed in the next release.
+const PARALLEL_THRESHOLD = 422500
+
+var pool atomic.Pointer[routines.Pool]
+
+// SetPool sets the worker pool used by parallel operations. Passing nil
+// goes back to routines.GlobalPool.
+func SetPool(p *routines.Pool) {
+	pool.Store(p)
+}
+
+// Pool returns the worker pool used by parallel operations.
+func Pool() *routines.Pool {
+	if p := pool.Load(); p != nil {
+		return p
+	}
+	return routines.GlobalPool
+}
+
+func init() {
+	for op, t := range defaultThresholds {
+		thresholds[op].Store(t)
+	}
+}
+
+// ParallelThreshold returns the estimated work above which op is split
+// across the worker pool.
+func ParallelThreshold(op Op) int {
+	return int(thresholds[op].Load())
+}
+
+// SetParallelThreshold changes the work above which op runs in parallel. A
+// negative value restores the default.
+func SetParallelThreshold(op Op, work int) {
+	if work < 0 {
+		thresholds[op].Store(defaultThresholds[op])
+		return
+	}
+	thresholds[op].Store(int64(work))
+}
+
+// parallelize runs fn over [0, n) either inline or split across the pool,
+// depending on whether work exceeds the threshold for op. A panic in any
+// chunk is returned as a *routines.PanicError on either path. A pool with
+// fewer than two workers, including one that has been closed, runs
+// everything inline.
+func parallelize(op Op, work, n int, fn func(start, end int)) error {
+	if work <= ParallelThreshold(op) {
+		return runInline(n, fn)
+	}
+	// The size is read once: a closed pool has no workers, and a Close
+	// between two reads would leave nothing to divide by.
+	p := Pool()
+	size := p.Size()
+	if size < 2 {
+		return runInline(n, fn)
+	}
+	return p.ParallelFor(n, (n+size-1)/size, fn, routines.WithLabel("matrix."+op.String()))
+}
+
+// runInline calls fn over [0, n) on the calling goroutine, turning a panic
+// into a *routines.PanicError as the pool would.
+func runInline(n int, fn func(start, end int)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &routines.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	fn(0, n)
+	return nil
+}
+
+// Crossover is the result of calibrating one operation: the smallest
+// measured work at which the parallel path beat the serial one, together
+// with the timings at that size.
+type Crossover struct {
+	Op       Op
+	Work     int
+	Serial   time.Duration
+	Parallel time.Duration
+	Found    bool
+}
+
+// Calibrate times every operation serially and in parallel on doubling
+// input sizes, stores the observed crossover as the new threshold and
+// returns the measurements. Operations that never got faster in parallel up
+// to maxWork keep their current threshold. Thresholds are switched while
+// measuring, so call it before starting work that uses the matrix package.
+func Calibrate(maxWork int) []Crossover {
+	results := make([]Crossover, 0, numOps)
+	for _, op := range Ops() {
+		if Pool().Size() < 2 {
+			// Nothing ever runs in parallel on a single worker.
+			results = append(results, Crossover{Op: op})
+			continue
+		}
+		results = append(results, calibrateOp(op, maxWork))
+	}
+	return results
+}
+
+func calibrateOp(op Op, maxWork int) Crossover {
+	saved := ParallelThreshold(op)
+	result := Crossover{Op: op}
+	for work := 1 << 10; work <= maxWork; work <<= 1 {
+		run := benchmarkRunner(op, work)
+
+		SetParallelThreshold(op, int(^uint(0)>>1))
+		serial := timeRuns(run)
+		SetParallelThreshold(op, 0)
+		parallel := timeRuns(run)
+
+		// Require a clear win so that timer noise does not pick a
+		// crossover that is too small.
+		if parallel < serial*9/10 {
+			result.Work = work
+			result.Serial = serial
+			result.Parallel = parallel
+			result.Found = true
+			break
+		}
+	}
+	if result.Found {
+		SetParallelThreshold(op, result.Work)
+	} else {
+		SetParallelThreshold(op, saved)
+	}
+	return result
+}
+
+// benchmarkRunner builds inputs for op whose estimated work is roughly the
+// requested amount and returns a closure performing the operation once.
+func benchmarkRunner(op Op, work int) func() {
+	switch op {
+	case OpDotProduct:
+		side := 1
+		for side*side*side < work {
+			side++
+		}
+		a := NewMatrix(side, side, make([]float64, side*side))
+		b := NewMatrix(side, side, make([]float64, side*side))
+		out := NewMatrix(side, side, make([]float64, side*side))
+		return func() { DotProduct(a, b, out) }
+	case OpSparseDotProduct:
+		side := 1
+		for side*side*side < work {
+			side++
+		}
+		dense := NewMatrix(side, side, make([]float64, side*side))
+		for i := range dense.Data {
+			dense.Data[i] = 1
+		}
+		s := SparseFromDense(dense)
+		out := NewMatrix(side, side, make([]float64, side*side))
+		return func() { SparseDotProduct(s, dense, out) }
+	case OpTranspose:
+		m := NewMatrix(1, work, make([]float64, work))
+		return func() { Transpose(m) }
+	case OpElementWise:
+		m := NewMatrix(1, work, make([]float64, work))
+		return func() { Add(m, m, m) }
+	case OpScalar:
+		m := NewMatrix(1, work, make([]float64, work))
+		return func() { MultiplyScalar(m, 1.0001, m) }
+	default:
+		m := NewMatrix(1, work, make([]float64, work))
+		return func() { ApplyFunction(m, func(x float64) float64 { return x * 0.5 }, m) }
+	}
+}
+
+// timeRuns returns the best of a few runs to filter out scheduling noise.
+func timeRuns(run func()) time.Duration {
+	run()
+	best := time.Duration(1<<63 - 1)
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		run()
+		if d := time.Since(start); d < best {
+			best = d
+		}
+	}
+	return best
+}
@@ -0,0 +1,123 @@
+package matrix
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+func TestParallelizeRecoversPanics(t *testing.T) {
+	for _, mode := range []struct {
+		name      string
+		threshold int
+	}{
+		{"inline", int(^uint(0) >> 1)},
+		{"parallel", 0},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			saved := ParallelThreshold(OpElementWise)
+			SetParallelThreshold(OpElementWise, mode.threshold)
+			defer SetParallelThreshold(OpElementWise, saved)
+
+			err := parallelize(OpElementWise, 1000, 1000, func(start, end int) { panic("boom") })
+			var panicErr *routines.PanicError
+			if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+				t.Fatalf("parallelize error = %v, want a *routines.PanicError", err)
+			}
+		})
+	}
+}
+
+func TestParallelizeOnSmallOrClosedPool(t *testing.T) {
+	saved := ParallelThreshold(OpElementWise)
+	SetParallelThreshold(OpElementWise, 0)
+	defer SetParallelThreshold(OpElementWise, saved)
+	defer SetPool(nil)
+
+	single := routines.NewPool(routines.WithWorkers(1))
+	defer single.Close()
+	closed := routines.NewPool(routines.WithWorkers(4))
+	closed.Close()
+	for _, tt := range []struct {
+		name string
+		pool *routines.Pool
+	}{
+		{"one worker", single},
+		{"closed", closed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPool(tt.pool)
+			var calls [][2]int
+			err := parallelize(OpElementWise, 1000, 1000, func(start, end int) {
+				calls = append(calls, [2]int{start, end})
+			})
+			if err != nil || len(calls) != 1 || calls[0] != [2]int{0, 1000} {
+				t.Fatalf("parallelize ran %v, %v, want one inline call over [0, 1000)", calls, err)
+			}
+		})
+	}
+}
+
+// BenchmarkCrossover times every operation serially and in parallel on
+// doubling work sizes. The crossover for an op is the smallest size where
+// the parallel sub-benchmark is faster; Calibrate finds the same point at
+// run time.
+func BenchmarkCrossover(b *testing.B) {
+	for _, op := range Ops() {
+		saved := ParallelThreshold(op)
+		for work := 1 << 12; work <= 1<<20; work <<= 2 {
+			run := benchmarkRunner(op, work)
+			for _, mode := range []struct {
+				name      string
+				threshold int
+			}{
+				{"serial", int(^uint(0) >> 1)},
+				{"parallel", 0},
+			} {
+				b.Run(fmt.Sprintf("%s/work=%d/%s", op, work, mode.name), func(b *testing.B) {
+					SetParallelThreshold(op, mode.threshold)
+					defer SetParallelThreshold(op, saved)
+					for i := 0; i < b.N; i++ {
+						run()
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestSetPool(t *testing.T) {
+	saved := ParallelThreshold(OpDotProduct)
+	SetParallelThreshold(OpDotProduct, 0)
+	defer SetParallelThreshold(OpDotProduct, saved)
+	defer SetPool(nil)
+
+	a := fromRows([][]float64{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {1, 0, 1}})
+	b := fromRows([][]float64{{1, 0}, {0, 1}, {1, 1}})
+	want := fromRows([][]float64{{4, 5}, {10, 11}, {16, 17}, {2, 1}})
+
+	p := routines.NewPool(routines.WithWorkers(4))
+	defer p.Close()
+	SetPool(p)
+	if Pool() != p {
+		t.Fatal("Pool() does not return the pool set with SetPool")
+	}
+	assertMatrix(t, "product on the set pool", mul(t, a, b), want)
+	if p.Stats().Submitted == 0 {
+		t.Fatal("DotProduct did not use the pool set with SetPool")
+	}
+
+	// Swapping to a closed pool keeps working, inline.
+	closed := routines.NewPool(routines.WithWorkers(4))
+	closed.Close()
+	SetPool(closed)
+	assertMatrix(t, "product on a closed pool", mul(t, a, b), want)
+
+	SetPool(nil)
+	if Pool() != routines.GlobalPool {
+		t.Fatal("SetPool(nil) does not go back to routines.GlobalPool")
+	}
+	assertMatrix(t, "product on the global pool", mul(t, a, b), want)
+}
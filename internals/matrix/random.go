@@ -0,0 +1,49 @@
+package matrix
+
+import (
+	"math"
+	"math/rand"
+)
+
+// NewRandomUniform fills a rows×cols matrix with values drawn uniformly from
+// [low, high).
+func NewRandomUniform(rows, cols int, low, high float64, rng *rand.Rand) *Matrix {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = low + rng.Float64()*(high-low)
+	}
+	return NewMatrix(rows, cols, data)
+}
+
+func NewRandomNormal(rows, cols int, mean, stdDev float64, rng *rand.Rand) *Matrix {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = mean + rng.NormFloat64()*stdDev
+	}
+	return NewMatrix(rows, cols, data)
+}
+
+// NewRandomTruncatedNormal draws from a normal distribution, redrawing any
+// value more than two standard deviations from the mean.
+func NewRandomTruncatedNormal(rows, cols int, mean, stdDev float64, rng *rand.Rand) *Matrix {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		z := rng.NormFloat64()
+		for math.Abs(z) > 2 {
+			z = rng.NormFloat64()
+		}
+		data[i] = mean + z*stdDev
+	}
+	return NewMatrix(rows, cols, data)
+}
+
+// NewRandomBernoulli sets each entry to 1 with probability p and 0 otherwise.
+func NewRandomBernoulli(rows, cols int, p float64, rng *rand.Rand) *Matrix {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		if rng.Float64() < p {
+			data[i] = 1
+		}
+	}
+	return NewMatrix(rows, cols, data)
+}
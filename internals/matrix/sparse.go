@@ -0,0 +1,172 @@
+package matrix
+
+import "errors"
+
+// SparseMatrix stores a matrix in compressed sparse row (CSR) form. The
+// non-zero values of row i are Values[RowPtr[i]:RowPtr[i+1]], with their
+// column positions in the same range of ColIndices.
+type SparseMatrix struct {
+	Rows       int
+	Cols       int
+	Values     []float64
+	ColIndices []int
+	RowPtr     []int
+}
+
+// NewSparseMatrix checks the CSR arrays and wraps them without copying. The
+// column indices of every row must be in range, sorted and unique.
+func NewSparseMatrix(rows, cols int, values []float64, colIndices, rowPtr []int) (*SparseMatrix, error) {
+	if rows < 0 || cols < 0 {
+		return nil, errors.New("Matrix dimensions must not be negative")
+	}
+	if len(rowPtr) != rows+1 {
+		return nil, errors.New("Row pointer length must be rows+1")
+	}
+	if len(values) != len(colIndices) {
+		return nil, errors.New("Values and column indices lengths do not match")
+	}
+	if rowPtr[0] != 0 || rowPtr[rows] != len(values) {
+		return nil, errors.New("Row pointers do not cover the stored values")
+	}
+	for i := 0; i < rows; i++ {
+		if rowPtr[i] > rowPtr[i+1] {
+			return nil, errors.New("Row pointers must be non-decreasing")
+		}
+	}
+	for i := 0; i < rows; i++ {
+		for k := rowPtr[i]; k < rowPtr[i+1]; k++ {
+			c := colIndices[k]
+			if c < 0 || c >= cols {
+				return nil, errors.New("Column index out of range")
+			}
+			if k > rowPtr[i] && c <= colIndices[k-1] {
+				return nil, errors.New("Column indices of a row must be sorted and unique")
+			}
+		}
+	}
+	return &SparseMatrix{
+		Rows:       rows,
+		Cols:       cols,
+		Values:     values,
+		ColIndices: colIndices,
+		RowPtr:     rowPtr,
+	}, nil
+}
+
+// NewOneHot builds a len(indices)×cols matrix with a single 1 per row at the
+// given column, which is how class labels are expanded without allocating
+// the dense rows.
+func NewOneHot(cols int, indices []int) (*SparseMatrix, error) {
+	values := make([]float64, len(indices))
+	colIndices := make([]int, len(indices))
+	rowPtr := make([]int, len(indices)+1)
+	for i, idx := range indices {
+		if idx < 0 || idx >= cols {
+			return nil, errors.New("Class index out of range")
+		}
+		values[i] = 1
+		colIndices[i] = idx
+		rowPtr[i+1] = i + 1
+	}
+	return &SparseMatrix{
+		Rows:       len(indices),
+		Cols:       cols,
+		Values:     values,
+		ColIndices: colIndices,
+		RowPtr:     rowPtr,
+	}, nil
+}
+
+func SparseFromDense(m *Matrix) *SparseMatrix {
+	s := &SparseMatrix{
+		Rows:   m.Rows,
+		Cols:   m.Cols,
+		RowPtr: make([]int, m.Rows+1),
+	}
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if v := Get(i, j, m); v != 0 {
+				s.Values = append(s.Values, v)
+				s.ColIndices = append(s.ColIndices, j)
+			}
+		}
+		s.RowPtr[i+1] = len(s.Values)
+	}
+	return s
+}
+
+func (s *SparseMatrix) ToDense() *Matrix {
+	out := NewMatrix(s.Rows, s.Cols, make([]float64, s.Rows*s.Cols))
+	for i := 0; i < s.Rows; i++ {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			Set(i, s.ColIndices[k], s.Values[k], out)
+		}
+	}
+	return out
+}
+
+func (s *SparseMatrix) NNZ() int {
+	return len(s.Values)
+}
+
+func SparseGet(row, col int, s *SparseMatrix) float64 {
+	for k := s.RowPtr[row]; k < s.RowPtr[row+1]; k++ {
+		if s.ColIndices[k] == col {
+			return s.Values[k]
+		}
+	}
+	return 0
+}
+
+// SparseDotProduct computes s × m into the dense matrix out.
+func SparseDotProduct(s *SparseMatrix, m, out *Matrix) error {
+	if s.Cols != m.Rows {
+		return errors.New("Matrix dimensions are incompatable")
+	}
+	if out.Rows != s.Rows || out.Cols != m.Cols {
+		return errors.New("Output matrix has the wrong dimensions")
+	}
+	c2 := m.Cols
+	rowRange := func(startRow, endRow int) {
+		for row := startRow; row < endRow; row++ {
+			outRow := out.Data[row*c2 : (row+1)*c2]
+			for j := range outRow {
+				outRow[j] = 0
+			}
+			for k := s.RowPtr[row]; k < s.RowPtr[row+1]; k++ {
+				v := s.Values[k]
+				mRow := m.Data[s.ColIndices[k]*c2 : (s.ColIndices[k]+1)*c2]
+				for j, w := range mRow {
+					outRow[j] += v * w
+				}
+			}
+		}
+	}
+	return parallelize(OpSparseDotProduct, s.NNZ()*c2, s.Rows, rowRange)
+}
+
+// SparseTransposeDotProduct computes sᵀ × m into out without materialising
+// the transpose. This is the weight gradient shape for sparse layer inputs.
+func SparseTransposeDotProduct(s *SparseMatrix, m, out *Matrix) error {
+	if s.Rows != m.Rows {
+		return errors.New("Matrix dimensions are incompatable")
+	}
+	if out.Rows != s.Cols || out.Cols != m.Cols {
+		return errors.New("Output matrix has the wrong dimensions")
+	}
+	c2 := m.Cols
+	for i := range out.Data {
+		out.Data[i] = 0
+	}
+	for row := 0; row < s.Rows; row++ {
+		mRow := m.Data[row*c2 : (row+1)*c2]
+		for k := s.RowPtr[row]; k < s.RowPtr[row+1]; k++ {
+			v := s.Values[k]
+			outRow := out.Data[s.ColIndices[k]*c2 : (s.ColIndices[k]+1)*c2]
+			for j, w := range mRow {
+				outRow[j] += v * w
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package matrix
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomSparse returns a rows×cols dense matrix in which about density of
+// the entries are non-zero.
+func randomSparse(rows, cols int, density float64, rng *rand.Rand) *Matrix {
+	m := NewMatrix(rows, cols, make([]float64, rows*cols))
+	for i := range m.Data {
+		if rng.Float64() < density {
+			m.Data[i] = rng.NormFloat64()
+		}
+	}
+	return m
+}
+
+func randomDense(rows, cols int, rng *rand.Rand) *Matrix {
+	return randomSparse(rows, cols, 1, rng)
+}
+
+func TestNewSparseMatrixRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows, cols int
+		values     []float64
+		colIndices []int
+		rowPtr     []int
+	}{
+		{"negative rows", -1, 2, nil, nil, nil},
+		{"negative cols", 1, -1, nil, nil, []int{0, 0}},
+		{"short row pointers", 2, 2, nil, nil, []int{0, 0}},
+		{"values without columns", 1, 2, []float64{1}, nil, []int{0, 1}},
+		{"row pointers from 1", 1, 2, []float64{1}, []int{0}, []int{1, 1}},
+		{"row pointers short of the values", 1, 2, []float64{1, 2}, []int{0, 1}, []int{0, 1}},
+		{"decreasing row pointers", 2, 2, []float64{1}, []int{0}, []int{0, 2, 1}},
+		{"negative column", 1, 2, []float64{1}, []int{-1}, []int{0, 1}},
+		{"column out of range", 1, 2, []float64{1}, []int{2}, []int{0, 1}},
+		{"unsorted columns", 1, 3, []float64{1, 2}, []int{2, 0}, []int{0, 2}},
+		{"duplicate columns", 1, 3, []float64{1, 2}, []int{1, 1}, []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSparseMatrix(tt.rows, tt.cols, tt.values, tt.colIndices, tt.rowPtr); err == nil {
+				t.Error("NewSparseMatrix succeeded")
+			}
+		})
+	}
+}
+
+func TestNewSparseMatrix(t *testing.T) {
+	// Columns only need to be ordered within a row.
+	s, err := NewSparseMatrix(3, 3, []float64{1, 2, 3}, []int{0, 2, 1}, []int{0, 2, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fromRows([][]float64{{1, 0, 2}, {0, 0, 0}, {0, 3, 0}})
+	assertMatrix(t, "ToDense()", s.ToDense(), want)
+
+	empty, err := NewSparseMatrix(0, 4, nil, nil, []int{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := empty.ToDense(); d.Rows != 0 || d.Cols != 4 || len(d.Data) != 0 {
+		t.Errorf("empty ToDense() is %dx%d with %d values", d.Rows, d.Cols, len(d.Data))
+	}
+}
+
+func TestSparseConversions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, density := range []float64{0, 0.1, 0.5, 1} {
+		dense := randomSparse(13, 7, density, rng)
+		s := SparseFromDense(dense)
+
+		nonZero := 0
+		for _, v := range dense.Data {
+			if v != 0 {
+				nonZero++
+			}
+		}
+		if s.NNZ() != nonZero {
+			t.Errorf("density %v: NNZ() = %d, want %d", density, s.NNZ(), nonZero)
+		}
+		if _, err := NewSparseMatrix(s.Rows, s.Cols, s.Values, s.ColIndices, s.RowPtr); err != nil {
+			t.Errorf("density %v: SparseFromDense built an invalid matrix: %v", density, err)
+		}
+		assertMatrix(t, "ToDense()", s.ToDense(), dense)
+		for i := 0; i < dense.Rows; i++ {
+			for j := 0; j < dense.Cols; j++ {
+				if got, want := SparseGet(i, j, s), Get(i, j, dense); got != want {
+					t.Fatalf("density %v: SparseGet(%d, %d) = %v, want %v", density, i, j, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestNewOneHot(t *testing.T) {
+	s, err := NewOneHot(3, []int{2, 0, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fromRows([][]float64{{0, 0, 1}, {1, 0, 0}, {1, 0, 0}, {0, 1, 0}})
+	assertMatrix(t, "ToDense()", s.ToDense(), want)
+
+	if _, err := NewOneHot(3, []int{3}); err == nil {
+		t.Error("NewOneHot accepted a class out of range")
+	}
+}
+
+func TestSparseDotProductMatchesDense(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	// The large shape is well above the threshold for running in parallel.
+	shapes := [][3]int{{1, 1, 1}, {5, 3, 4}, {17, 9, 1}, {300, 200, 50}}
+	for _, shape := range shapes {
+		rows, inner, cols := shape[0], shape[1], shape[2]
+		for _, density := range []float64{0, 0.05, 1} {
+			a := randomSparse(rows, inner, density, rng)
+			s := SparseFromDense(a)
+
+			m := randomDense(inner, cols, rng)
+			out := randomDense(rows, cols, rng) // stale values must be overwritten
+			if err := SparseDotProduct(s, m, out); err != nil {
+				t.Fatal(err)
+			}
+			assertMatrix(t, "SparseDotProduct", out, mul(t, a, m))
+
+			m = randomDense(rows, cols, rng)
+			out = randomDense(inner, cols, rng)
+			if err := SparseTransposeDotProduct(s, m, out); err != nil {
+				t.Fatal(err)
+			}
+			assertMatrix(t, "SparseTransposeDotProduct", out, mul(t, Transpose(a), m))
+		}
+	}
+}
+
+func TestSparseDotProductDimensions(t *testing.T) {
+	s := SparseFromDense(NewMatrix(2, 3, make([]float64, 6)))
+	tests := []struct {
+		name   string
+		m, out *Matrix
+		fn     func(*SparseMatrix, *Matrix, *Matrix) error
+	}{
+		{"product inner", NewMatrix(2, 4, make([]float64, 8)), NewMatrix(2, 4, make([]float64, 8)), SparseDotProduct},
+		{"product output", NewMatrix(3, 4, make([]float64, 12)), NewMatrix(3, 4, make([]float64, 12)), SparseDotProduct},
+		{"transpose inner", NewMatrix(3, 4, make([]float64, 12)), NewMatrix(3, 4, make([]float64, 12)), SparseTransposeDotProduct},
+		{"transpose output", NewMatrix(2, 4, make([]float64, 8)), NewMatrix(2, 4, make([]float64, 8)), SparseTransposeDotProduct},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(s, tt.m, tt.out); err == nil {
+			t.Errorf("%s: mismatched dimensions were accepted", tt.name)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package matrix
+
+import "math"
+
+// ColumnMean returns a 1×Cols matrix with the mean of every column.
+func ColumnMean(m *Matrix) *Matrix {
+	out := NewMatrix(1, m.Cols, make([]float64, m.Cols))
+	if m.Rows == 0 {
+		return out
+	}
+	for i := 0; i < m.Rows; i++ {
+		row := m.Data[i*m.Cols : (i+1)*m.Cols]
+		for j, v := range row {
+			out.Data[j] += v
+		}
+	}
+	for j := range out.Data {
+		out.Data[j] /= float64(m.Rows)
+	}
+	return out
+}
+
+// ColumnVariance returns the population variance of every column as a
+// 1×Cols matrix.
+func ColumnVariance(m *Matrix) *Matrix {
+	mean := ColumnMean(m)
+	out := NewMatrix(1, m.Cols, make([]float64, m.Cols))
+	if m.Rows == 0 {
+		return out
+	}
+	for i := 0; i < m.Rows; i++ {
+		row := m.Data[i*m.Cols : (i+1)*m.Cols]
+		for j, v := range row {
+			d := v - mean.Data[j]
+			out.Data[j] += d * d
+		}
+	}
+	for j := range out.Data {
+		out.Data[j] /= float64(m.Rows)
+	}
+	return out
+}
+
+func ColumnStdDev(m *Matrix) *Matrix {
+	out := ColumnVariance(m)
+	for j, v := range out.Data {
+		out.Data[j] = math.Sqrt(v)
+	}
+	return out
+}
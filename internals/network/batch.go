@@ -0,0 +1,122 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/whyisemerald/neural_network/internals/matrix"
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+// predictBlockRows bounds how many samples go through the network as one
+// matrix, which keeps the intermediate activations small for large batches
+// and gives the pool independent blocks to work on.
+const predictBlockRows = 1024
+
+// PredictMatrix runs every row of inputs through the network at once and
+// returns the outputs as a len(rows)×outputs matrix. Each block of rows is
+// a chain of matrix products, and blocks run in parallel on the matrix pool.
+// Only the weights are read, so it is safe to call concurrently.
+func (n *Network) PredictMatrix(inputs *matrix.Matrix) (*matrix.Matrix, error) {
+	if inputs.Cols != n.InputSize() {
+		return nil, errors.New("Input size does not match the number of inputs of the network")
+	}
+	outputSize := n.Layers[len(n.Layers)-1].numNeurons
+	out := matrix.NewMatrix(inputs.Rows, outputSize, make([]float64, inputs.Rows*outputSize))
+	numBlocks := (inputs.Rows + predictBlockRows - 1) / predictBlockRows
+
+	err := matrix.Pool().ParallelFor(numBlocks, 1, func(startBlock, endBlock int) {
+		for block := startBlock; block < endBlock; block++ {
+			start := block * predictBlockRows
+			end := min(start+predictBlockRows, inputs.Rows)
+			current := matrix.NewMatrix(end-start, inputs.Cols, inputs.Data[start*inputs.Cols:end*inputs.Cols])
+			if n.Normalizer != nil || n.Encoder != nil {
+				current = n.loadRows(current)
+			}
+			for _, layer := range n.Layers {
+				next := matrix.NewMatrix(current.Rows, layer.numNeurons, make([]float64, current.Rows*layer.numNeurons))
+				// Shapes were checked above, so this cannot fail.
+				matrix.DotProduct(current, layer.Weights, next)
+				matrix.AddRowVector(next, layer.Biases, next)
+				matrix.ApplyFunction(next, layer.activation, next)
+				current = next
+			}
+			copy(out.Data[start*outputSize:end*outputSize], current.Data)
+		}
+	}, routines.WithSchedule(routines.Dynamic), routines.WithLabel("network.PredictMatrix"))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// loadRows returns the first layer's inputs for every row of m, normalized
+// and encoded like in forward.
+func (n *Network) loadRows(m *matrix.Matrix) *matrix.Matrix {
+	size := n.Layers[0].numInputs
+	out := matrix.NewMatrix(m.Rows, size, make([]float64, m.Rows*size))
+	normalized := make([]float64, m.Cols)
+	for i := 0; i < m.Rows; i++ {
+		n.loadInputs(out.Data[i*size:(i+1)*size], normalized, m.Data[i*m.Cols:(i+1)*m.Cols])
+	}
+	return out
+}
+
+// PredictBatch is PredictMatrix for a slice of input vectors.
+func (n *Network) PredictBatch(inputs [][]float64) ([][]float64, error) {
+	m, err := batchMatrix(inputs, n.InputSize())
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.PredictMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([][]float64, out.Rows)
+	for i := range outputs {
+		outputs[i] = out.Data[i*out.Cols : (i+1)*out.Cols : (i+1)*out.Cols]
+	}
+	return outputs, nil
+}
+
+// PredictClasses returns the index of the largest output for every input.
+func (n *Network) PredictClasses(inputs [][]float64) ([]int, error) {
+	m, err := batchMatrix(inputs, n.InputSize())
+	if err != nil {
+		return nil, err
+	}
+	out, err := n.PredictMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]int, out.Rows)
+	for i := range classes {
+		classes[i] = argmax(out.Data[i*out.Cols : (i+1)*out.Cols])
+	}
+	return classes, nil
+}
+
+// ClassifyBatch is PredictBatch that also returns the index of the largest
+// output for every input.
+func (n *Network) ClassifyBatch(inputs [][]float64) ([]int, [][]float64, error) {
+	outputs, err := n.PredictBatch(inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	classes := make([]int, len(outputs))
+	for i, out := range outputs {
+		classes[i] = argmax(out)
+	}
+	return classes, outputs, nil
+}
+
+// batchMatrix packs equally sized input vectors into the rows of a matrix.
+func batchMatrix(inputs [][]float64, size int) (*matrix.Matrix, error) {
+	data := make([]float64, len(inputs)*size)
+	for i, in := range inputs {
+		if len(in) != size {
+			return nil, errors.New("Input size does not match the number of inputs of the network")
+		}
+		copy(data[i*size:], in)
+	}
+	return matrix.NewMatrix(len(inputs), size, data), nil
+}
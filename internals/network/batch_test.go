@@ -0,0 +1,88 @@
+package network
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPredictBatchMatchesForward(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	encoded, inputs := encodedNetwork(t, rng)
+	plain := NewNetwork([]int{2, 8, 5})
+	// More rows than one prediction block, so several blocks run in
+	// parallel.
+	for len(inputs) < 3*predictBlockRows+7 {
+		inputs = append(inputs, []float64{68 + 30*rng.Float64(), 8 + 29*rng.Float64()})
+	}
+
+	for name, n := range map[string]*Network{"plain": plain, "encoded": encoded} {
+		t.Run(name, func(t *testing.T) {
+			outputs, err := n.PredictBatch(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			classes, err := n.PredictClasses(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			batchClasses, batchOutputs, err := n.ClassifyBatch(inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outputs) != len(inputs) || len(classes) != len(inputs) || len(batchClasses) != len(inputs) || len(batchOutputs) != len(inputs) {
+				t.Fatalf("got %d outputs, %d classes and %d, %d from ClassifyBatch for %d inputs",
+					len(outputs), len(classes), len(batchClasses), len(batchOutputs), len(inputs))
+			}
+			for i, x := range inputs {
+				want, err := n.Forward(x)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for j := range want {
+					if math.Abs(outputs[i][j]-want[j]) > 1e-12 || math.Abs(batchOutputs[i][j]-want[j]) > 1e-12 {
+						t.Fatalf("row %d: PredictBatch %v, ClassifyBatch %v, Forward %v", i, outputs[i], batchOutputs[i], want)
+					}
+				}
+				if classes[i] != argmax(want) || batchClasses[i] != argmax(want) {
+					t.Fatalf("row %d: PredictClasses %d, ClassifyBatch %d, want %d", i, classes[i], batchClasses[i], argmax(want))
+				}
+			}
+		})
+	}
+}
+
+func TestPredictBatchInputSize(t *testing.T) {
+	n := NewNetwork([]int{2, 4, 3})
+	bad := [][]float64{{1, 2}, {1, 2, 3}}
+	if _, err := n.PredictBatch(bad); err == nil {
+		t.Error("PredictBatch accepted a row of the wrong size")
+	}
+	if _, err := n.PredictClasses(bad); err == nil {
+		t.Error("PredictClasses accepted a row of the wrong size")
+	}
+	if _, _, err := n.ClassifyBatch(bad); err == nil {
+		t.Error("ClassifyBatch accepted a row of the wrong size")
+	}
+	if _, err := n.PredictBatch([][]float64{{1}}); err == nil {
+		t.Error("PredictBatch accepted a short row")
+	}
+}
+
+func TestPredictBatchEmpty(t *testing.T) {
+	n := NewNetwork([]int{2, 4, 3})
+	for _, inputs := range [][][]float64{nil, {}} {
+		outputs, err := n.PredictBatch(inputs)
+		if err != nil || len(outputs) != 0 {
+			t.Errorf("PredictBatch(%v) = %v, %v", inputs, outputs, err)
+		}
+		classes, err := n.PredictClasses(inputs)
+		if err != nil || len(classes) != 0 {
+			t.Errorf("PredictClasses(%v) = %v, %v", inputs, classes, err)
+		}
+		classes, outputs, err = n.ClassifyBatch(inputs)
+		if err != nil || len(classes) != 0 || len(outputs) != 0 {
+			t.Errorf("ClassifyBatch(%v) = %v, %v, %v", inputs, classes, outputs, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package network
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// Encoder kinds.
+const (
+	EncodeSinCos  = "sincos"
+	EncodeFourier = "fourier"
+)
+
+// Encoder expands the normalized inputs into the features the first layer
+// sees, so that a small network can resolve sharp borders. The features are
+// the normalized inputs themselves, then the periodic features of Kind, then
+// the unit sphere position of the raw inputs if Sphere is set. An empty Kind
+// adds no periodic features. It is saved with the model.
+type Encoder struct {
+	Kind string `json:",omitempty"`
+	// Inputs is the number of inputs the encoder reads.
+	Inputs int
+	// Frequencies is the number of octaves per input for sincos, or the
+	// number of random frequencies for fourier.
+	Frequencies int `json:",omitempty"`
+	// Scale is the standard deviation the fourier frequencies were drawn
+	// with.
+	Scale float64 `json:",omitempty"`
+	// Projection holds the fourier frequencies, Frequencies rows of Inputs
+	// values.
+	Projection []float64 `json:",omitempty"`
+	// Sphere appends x, y and z on the unit sphere of the first two raw
+	// inputs, read as longitude and latitude in degrees.
+	Sphere bool `json:",omitempty"`
+}
+
+// NewSinCosEncoder encodes every input x as sin(2^k·π·x) and cos(2^k·π·x)
+// for k in [0, frequencies).
+func NewSinCosEncoder(inputs, frequencies int) (*Encoder, error) {
+	e := &Encoder{Kind: EncodeSinCos, Inputs: inputs, Frequencies: frequencies}
+	return e, e.validate()
+}
+
+// NewFourierEncoder encodes the inputs x as sin(2π·b·x) and cos(2π·b·x) for
+// frequencies random vectors b drawn from a normal distribution with
+// standard deviation scale.
+func NewFourierEncoder(inputs, frequencies int, scale float64, rng *rand.Rand) (*Encoder, error) {
+	e := &Encoder{Kind: EncodeFourier, Inputs: inputs, Frequencies: frequencies, Scale: scale}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	e.Projection = make([]float64, frequencies*inputs)
+	for i := range e.Projection {
+		e.Projection[i] = rng.NormFloat64() * scale
+	}
+	return e, nil
+}
+
+func (e *Encoder) validate() error {
+	if e.Inputs <= 0 {
+		return errors.New("Encoder needs at least one input")
+	}
+	if e.Sphere && e.Inputs < 2 {
+		return errors.New("Sphere encoding needs a longitude and a latitude input")
+	}
+	switch e.Kind {
+	case "":
+	case EncodeSinCos:
+		if e.Frequencies <= 0 {
+			return errors.New("Encoder needs at least one frequency")
+		}
+	case EncodeFourier:
+		if e.Frequencies <= 0 {
+			return errors.New("Encoder needs at least one frequency")
+		}
+		if !(e.Scale > 0) || math.IsInf(e.Scale, 0) {
+			return errors.New("Fourier scale must be a positive number")
+		}
+		if e.Projection != nil && len(e.Projection) != e.Frequencies*e.Inputs {
+			return errors.New("Fourier projection does not match the number of inputs and frequencies")
+		}
+	default:
+		return errors.New("Unknown encoder kind " + e.Kind)
+	}
+	return nil
+}
+
+// Size is the number of features the encoder produces.
+func (e *Encoder) Size() int {
+	size := e.Inputs
+	switch e.Kind {
+	case EncodeSinCos:
+		size += 2 * e.Frequencies * e.Inputs
+	case EncodeFourier:
+		size += 2 * e.Frequencies
+	}
+	if e.Sphere {
+		size += 3
+	}
+	return size
+}
+
+// Apply writes the features of raw, whose normalized values are normalized,
+// into dst. raw and normalized have Inputs elements and dst has Size.
+func (e *Encoder) Apply(dst, raw, normalized []float64) {
+	k := copy(dst, normalized)
+	switch e.Kind {
+	case EncodeSinCos:
+		for _, x := range normalized {
+			for f := 0; f < e.Frequencies; f++ {
+				dst[k], dst[k+1] = math.Sincos(math.Ldexp(math.Pi*x, f))
+				k += 2
+			}
+		}
+	case EncodeFourier:
+		for f := 0; f < e.Frequencies; f++ {
+			row := e.Projection[f*e.Inputs : (f+1)*e.Inputs]
+			angle := 0.0
+			for j, x := range normalized {
+				angle += row[j] * x
+			}
+			dst[k], dst[k+1] = math.Sincos(2 * math.Pi * angle)
+			k += 2
+		}
+	}
+	if e.Sphere {
+		lon, lat := raw[0]*math.Pi/180, raw[1]*math.Pi/180
+		dst[k] = math.Cos(lat) * math.Cos(lon)
+		dst[k+1] = math.Cos(lat) * math.Sin(lon)
+		dst[k+2] = math.Sin(lat)
+	}
+}
+
+func (e *Encoder) clone() *Encoder {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Projection = append([]float64(nil), e.Projection...)
+	return &c
+}
+
+// SetEncoder installs e, or removes encoding when e is nil. The encoded
+// size must match the first layer and the normalizer, if any, must match
+// the encoder's inputs.
+func (n *Network) SetEncoder(e *Encoder) error {
+	inputs := n.Layers[0].numInputs
+	if e != nil {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		if e.Kind == EncodeFourier && e.Projection == nil {
+			return errors.New("Fourier encoder has no projection")
+		}
+		if e.Size() != n.Layers[0].numInputs {
+			return errors.New("Encoder size does not match the number of inputs of the first layer")
+		}
+		inputs = e.Inputs
+	}
+	if n.Normalizer != nil && n.Normalizer.Size() != inputs {
+		return errors.New("Normalizer size does not match the number of inputs of the encoder")
+	}
+	n.Encoder = e
+	return nil
+}
+
+// InputSize is the number of inputs the network takes: those of the encoder
+// if there is one, otherwise those of the first layer.
+func (n *Network) InputSize() int {
+	if n.Encoder != nil {
+		return n.Encoder.Inputs
+	}
+	return n.Layers[0].numInputs
+}
+
+// setInputTransforms replaces the normalizer and the encoder.
+func (n *Network) setInputTransforms(z *Normalizer, e *Encoder) error {
+	n.Normalizer = nil
+	if err := n.SetEncoder(e); err != nil {
+		return err
+	}
+	return n.SetNormalizer(z)
+}
@@ -0,0 +1,125 @@
+package network
+
+import (
+	"errors"
+	"sync"
+)
+
+// HogwildTrainer runs asynchronous SGD in the style of Hogwild!: several
+// goroutines train on disjoint shards of the data, each with its own
+// Workspace, and apply their per-sample updates to the shared weights
+// without any locking.
+//
+// Race tolerance: weight reads and writes from different goroutines are
+// unsynchronised on purpose. An update may be computed from weights another
+// goroutine is halfway through changing, and concurrent read-modify-writes
+// of the same weight can lose one of the two updates. For sparse problems
+// where updates rarely touch the same weights this costs little accuracy,
+// and for dense ones it behaves like SGD with some gradient noise. Weights
+// are plain float64 values, so a torn read cannot produce anything worse
+// than a stale value on 64-bit platforms. The race detector will report
+// these accesses; do not use this trainer under -race.
+type HogwildTrainer struct {
+	Network    *Network
+	workspaces []*Workspace
+}
+
+func NewHogwildTrainer(n *Network, workers int) *HogwildTrainer {
+	if workers < 1 {
+		workers = 1
+	}
+	workspaces := make([]*Workspace, workers)
+	for i := range workspaces {
+		workspaces[i] = n.NewWorkspace()
+	}
+	return &HogwildTrainer{
+		Network:    n,
+		workspaces: workspaces,
+	}
+}
+
+func (t *HogwildTrainer) Workers() int {
+	return len(t.workspaces)
+}
+
+// TrainEpoch makes one pass over the data, each worker taking a contiguous
+// shard.
+func (t *HogwildTrainer) TrainEpoch(inputs, expected [][]float64, learningRate float64) error {
+	if len(inputs) != len(expected) {
+		return errors.New("Inputs and expected outputs have different lengths")
+	}
+	return t.run(len(inputs), func(ws *Workspace, j int) error {
+		if _, err := t.Network.forward(ws, inputs[j]); err != nil {
+			return err
+		}
+		if err := t.Network.backward(ws, expected[j]); err != nil {
+			return err
+		}
+		return t.Network.update(ws, learningRate)
+	})
+}
+
+func (t *HogwildTrainer) TrainEpochClasses(inputs [][]float64, labels []int, learningRate float64) error {
+	if len(inputs) != len(labels) {
+		return errors.New("Inputs and labels have different lengths")
+	}
+	return t.run(len(inputs), func(ws *Workspace, j int) error {
+		if _, err := t.Network.forward(ws, inputs[j]); err != nil {
+			return err
+		}
+		if err := t.Network.backwardClass(ws, labels[j]); err != nil {
+			return err
+		}
+		return t.Network.update(ws, learningRate)
+	})
+}
+
+// TrainLoop runs TrainEpoch the given number of times, reporting progress
+// per epoch.
+func (t *HogwildTrainer) TrainLoop(inputs, expected [][]float64, learningRate float64, epoch int) error {
+	if len(inputs) != len(expected) {
+		return errors.New("Inputs and expected outputs have different lengths")
+	}
+	return trainLoop(1, epoch, func(int) error {
+		return t.TrainEpoch(inputs, expected, learningRate)
+	})
+}
+
+func (t *HogwildTrainer) TrainLoopClasses(inputs [][]float64, labels []int, learningRate float64, epoch int) error {
+	if len(inputs) != len(labels) {
+		return errors.New("Inputs and labels have different lengths")
+	}
+	return trainLoop(1, epoch, func(int) error {
+		return t.TrainEpochClasses(inputs, labels, learningRate)
+	})
+}
+
+// run hands each workspace a contiguous shard of [0, numSamples). The
+// workers are plain goroutines rather than pool tasks because they run for
+// the whole epoch and the matrix operations they call use the pool. A worker
+// stops its shard at its first error; the errors of all workers are
+// joined.
+func (t *HogwildTrainer) run(numSamples int, sample func(ws *Workspace, j int) error) error {
+	shard := (numSamples + len(t.workspaces) - 1) / len(t.workspaces)
+	errs := make([]error, len(t.workspaces))
+	var wg sync.WaitGroup
+	for i, ws := range t.workspaces {
+		start := i * shard
+		end := min(start+shard, numSamples)
+		if start >= end {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := start; j < end; j++ {
+				if err := sample(ws, j); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// quadrants returns n points in [-1, 1]² labelled with the quadrant they
+// fall in.
+func quadrants(n int, rng *rand.Rand) ([][]float64, []int) {
+	inputs := make([][]float64, n)
+	labels := make([]int, n)
+	for i := range inputs {
+		x, y := 2*rng.Float64()-1, 2*rng.Float64()-1
+		inputs[i] = []float64{x, y}
+		if x >= 0 {
+			labels[i] |= 1
+		}
+		if y >= 0 {
+			labels[i] |= 2
+		}
+	}
+	return inputs, labels
+}
+
+func accuracy(b *testing.B, n *Network, inputs [][]float64, labels []int) float64 {
+	b.Helper()
+	p := n.NewPredictor()
+	correct := 0
+	for i, x := range inputs {
+		class, err := p.PredictClass(x)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if class == labels[i] {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(inputs))
+}
+
+// BenchmarkConvergence trains the same initial network for a fixed number
+// of epochs with plain SGD, the synchronous data-parallel trainer and the
+// Hogwild trainer, and reports the training accuracy each reaches next to
+// the time it takes. Hogwild trades a little accuracy per epoch for not
+// waiting on the other workers.
+func BenchmarkConvergence(b *testing.B) {
+	const (
+		samples      = 2000
+		epochs       = 10
+		learningRate = 0.1
+		batchSize    = 32
+	)
+	rng := rand.New(rand.NewSource(1))
+	inputs, labels := quadrants(samples, rng)
+	base := NewNetwork([]int{2, 16, 4})
+
+	type trainer struct {
+		name  string
+		train func(n *Network) error
+	}
+	trainers := []trainer{
+		{"sgd", func(n *Network) error {
+			for e := 0; e < epochs; e++ {
+				for i := range inputs {
+					if err := n.TrainClass(inputs[i], labels[i], learningRate); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}},
+	}
+	for _, workers := range []int{2, 4} {
+		trainers = append(trainers, trainer{fmt.Sprintf("sync/workers=%d", workers), func(n *Network) error {
+			t := NewDataParallelTrainer(n, workers)
+			for e := 0; e < epochs; e++ {
+				for start := 0; start < samples; start += batchSize {
+					end := min(start+batchSize, samples)
+					if err := t.TrainBatchClasses(inputs[start:end], labels[start:end], learningRate); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}})
+	}
+	for _, workers := range []int{1, 2, 4} {
+		trainers = append(trainers, trainer{fmt.Sprintf("hogwild/workers=%d", workers), func(n *Network) error {
+			t := NewHogwildTrainer(n, workers)
+			for e := 0; e < epochs; e++ {
+				if err := t.TrainEpochClasses(inputs, labels, learningRate); err != nil {
+					return err
+				}
+			}
+			return nil
+		}})
+	}
+
+	for _, tt := range trainers {
+		b.Run(tt.name, func(b *testing.B) {
+			total := 0.0
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				n := base.Clone()
+				b.StartTimer()
+				if err := tt.train(n); err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				total += accuracy(b, n, inputs, labels)
+				b.StartTimer()
+			}
+			b.ReportMetric(total/float64(b.N), "accuracy")
+			b.ReportMetric(float64(samples*epochs)*float64(b.N)/b.Elapsed().Seconds(), "samples/s")
+		})
+	}
+}
@@ -16,31 +16,40 @@ type Layer struct {
 	numNeurons           int
 	numInputs            int
 
-	// Pre-allocated matrices
-	Output            *matrix.Matrix
-	Deltas            *matrix.Matrix
-	Inputs            *matrix.Matrix
-	rawOutput         *matrix.Matrix
-	weightGradients   *matrix.Matrix
-	biasGradients     *matrix.Matrix
-	errorMatrix       *matrix.Matrix
-	derivativeMatrix  *matrix.Matrix
+	// buffers are the activations used by the Layer methods themselves.
+	// Concurrent passes each bring their own through a Workspace.
+	buffers *layerBuffers
+
+	// Gradients summed over a mini-batch by AccumulateGradients.
+	weightGradientSum *matrix.Matrix
+	biasGradientSum   *matrix.Matrix
+}
+
+// layerBuffers holds everything one forward and backward pass writes for a
+// layer, so that passes sharing the weights do not share state.
+type layerBuffers struct {
+	output           *matrix.Matrix
+	deltas           *matrix.Matrix
+	inputs           *matrix.Matrix
+	sparseInputs     *matrix.SparseMatrix
+	rawOutput        *matrix.Matrix
+	weightGradients  *matrix.Matrix
+	biasGradients    *matrix.Matrix
+	errorMatrix      *matrix.Matrix
+	derivativeMatrix *matrix.Matrix
 }
 
 func NewLayer(numNeurons, numInputs int, activation, activationDerivative Activation) *Layer {
-	weightsData := make([]float64, numInputs*numNeurons)
-	for i := range weightsData {
-		weightsData[i] = rand.Float64() - 0.5
-	}
-	weights := matrix.NewMatrix(numInputs, numNeurons, weightsData)
+	return NewLayerWithRand(numNeurons, numInputs, activation, activationDerivative, rand.New(rand.NewSource(rand.Int63())))
+}
 
-	biasesData := make([]float64, numNeurons)
-	for i := range biasesData {
-		biasesData[i] = rand.Float64() - 0.5
-	}
-	biases := matrix.NewMatrix(1, numNeurons, biasesData)
+// NewLayerWithRand is NewLayer with weights and biases drawn from rng, for
+// reproducible initialisation.
+func NewLayerWithRand(numNeurons, numInputs int, activation, activationDerivative Activation, rng *rand.Rand) *Layer {
+	weights := matrix.NewRandomUniform(numInputs, numNeurons, -0.5, 0.5, rng)
+	biases := matrix.NewRandomUniform(1, numNeurons, -0.5, 0.5, rng)
 
-	return &Layer{
+	l := &Layer{
 		Weights:              weights,
 		Biases:               biases,
 		activation:           activation,
@@ -48,9 +57,20 @@ func NewLayer(numNeurons, numInputs int, activation, activationDerivative Activa
 		numNeurons:           numNeurons,
 		numInputs:            numInputs,
 
-		// Pre-allocate matrices. Assumes a batch size of 1.
-		Output:           matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
-		Deltas:           matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
+		weightGradientSum: matrix.NewMatrix(numInputs, numNeurons, make([]float64, numInputs*numNeurons)),
+		biasGradientSum:   matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
+	}
+	l.buffers = l.newBuffers()
+	return l
+}
+
+// newBuffers pre-allocates the matrices for one pass. Assumes a batch size
+// of 1.
+func (l *Layer) newBuffers() *layerBuffers {
+	numNeurons, numInputs := l.numNeurons, l.numInputs
+	return &layerBuffers{
+		output:           matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
+		deltas:           matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
 		rawOutput:        matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
 		weightGradients:  matrix.NewMatrix(numInputs, numNeurons, make([]float64, numInputs*numNeurons)),
 		biasGradients:    matrix.NewMatrix(1, numNeurons, make([]float64, numNeurons)),
@@ -59,25 +79,107 @@ func NewLayer(numNeurons, numInputs int, activation, activationDerivative Activa
 	}
 }
 
-func (l *Layer) Forward(inputs *matrix.Matrix) *matrix.Matrix {
-	l.Inputs = inputs
+func (l *Layer) Forward(inputs *matrix.Matrix) (*matrix.Matrix, error) {
+	return l.forward(l.buffers, inputs)
+}
+
+// ForwardSparse is Forward for CSR inputs, used when the layer input is a
+// wide one-hot or embedding vector with few non-zero entries.
+func (l *Layer) ForwardSparse(inputs *matrix.SparseMatrix) (*matrix.Matrix, error) {
+	return l.forwardSparse(l.buffers, inputs)
+}
+
+func (l *Layer) Update(learningRate float64) error {
+	return l.update(l.buffers, learningRate)
+}
+
+// AccumulateGradients adds the gradients of the last Backward pass to the
+// layer's mini-batch sums instead of applying them.
+func (l *Layer) AccumulateGradients() error {
+	return l.accumulateGradients(l.buffers)
+}
+
+func (l *Layer) forward(b *layerBuffers, inputs *matrix.Matrix) (*matrix.Matrix, error) {
+	b.inputs = inputs
+	b.sparseInputs = nil
 
 	// Calculations use pre-allocated matrices. Assumes a consistent batch size.
-	matrix.DotProduct(inputs, l.Weights, l.rawOutput)
-	matrix.Add(l.rawOutput, l.Biases, l.rawOutput)
-	matrix.ApplyFunction(l.rawOutput, l.activation, l.Output)
+	if err := matrix.DotProduct(inputs, l.Weights, b.rawOutput); err != nil {
+		return nil, err
+	}
+	if err := matrix.Add(b.rawOutput, l.Biases, b.rawOutput); err != nil {
+		return nil, err
+	}
+	matrix.ApplyFunction(b.rawOutput, l.activation, b.output)
 
-	return l.Output
+	return b.output, nil
 }
 
-func (l *Layer) Update(learningRate float64) {
-	inputsT := matrix.Transpose(l.Inputs)
-	matrix.DotProduct(inputsT, l.Deltas, l.weightGradients)
+func (l *Layer) forwardSparse(b *layerBuffers, inputs *matrix.SparseMatrix) (*matrix.Matrix, error) {
+	b.inputs = nil
+	b.sparseInputs = inputs
 
-	matrix.MultiplyScalar(l.weightGradients, learningRate, l.weightGradients)
+	if err := matrix.SparseDotProduct(inputs, l.Weights, b.rawOutput); err != nil {
+		return nil, err
+	}
+	if err := matrix.Add(b.rawOutput, l.Biases, b.rawOutput); err != nil {
+		return nil, err
+	}
+	matrix.ApplyFunction(b.rawOutput, l.activation, b.output)
 
-	matrix.Subtract(l.Weights, l.weightGradients, l.Weights)
+	return b.output, nil
+}
+
+func (l *Layer) update(b *layerBuffers, learningRate float64) error {
+	if err := l.computeWeightGradients(b); err != nil {
+		return err
+	}
+
+	matrix.MultiplyScalar(b.weightGradients, learningRate, b.weightGradients)
+
+	if err := matrix.Subtract(l.Weights, b.weightGradients, l.Weights); err != nil {
+		return err
+	}
+
+	matrix.MultiplyScalar(b.deltas, learningRate, b.biasGradients)
+	return matrix.Subtract(l.Biases, b.biasGradients, l.Biases)
+}
+
+func (l *Layer) computeWeightGradients(b *layerBuffers) error {
+	if b.sparseInputs != nil {
+		return matrix.SparseTransposeDotProduct(b.sparseInputs, b.deltas, b.weightGradients)
+	}
+	inputsT := matrix.Transpose(b.inputs)
+	return matrix.DotProduct(inputsT, b.deltas, b.weightGradients)
+}
+
+func (l *Layer) accumulateGradients(b *layerBuffers) error {
+	if err := l.computeWeightGradients(b); err != nil {
+		return err
+	}
+	if err := matrix.Add(l.weightGradientSum, b.weightGradients, l.weightGradientSum); err != nil {
+		return err
+	}
+	return matrix.Add(l.biasGradientSum, b.deltas, l.biasGradientSum)
+}
+
+// ApplyGradients takes a step along the mean of the accumulated gradients
+// over batchSize samples and clears the sums.
+func (l *Layer) ApplyGradients(learningRate float64, batchSize int) error {
+	scale := learningRate / float64(batchSize)
+	b := l.buffers
+	defer l.ResetGradients()
+
+	matrix.MultiplyScalar(l.weightGradientSum, scale, b.weightGradients)
+	if err := matrix.Subtract(l.Weights, b.weightGradients, l.Weights); err != nil {
+		return err
+	}
+
+	matrix.MultiplyScalar(l.biasGradientSum, scale, b.biasGradients)
+	return matrix.Subtract(l.Biases, b.biasGradients, l.Biases)
+}
 
-	matrix.MultiplyScalar(l.Deltas, learningRate, l.biasGradients)
-	matrix.Subtract(l.Biases, l.biasGradients, l.Biases)
+func (l *Layer) ResetGradients() {
+	clear(l.weightGradientSum.Data)
+	clear(l.biasGradientSum.Data)
 }
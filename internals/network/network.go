@@ -2,6 +2,7 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -16,10 +17,29 @@ type Network struct {
 	Layers         []*Layer
 	InputMatrix    *matrix.Matrix
 	ExpectedMatrix *matrix.Matrix
+
+	// Classes names the output units, if the network is a classifier. It is
+	// saved with the model.
+	Classes []string
+
+	// Normalizer, if set, transforms every input before the first layer.
+	// Set it with SetNormalizer. It is saved with the model.
+	Normalizer *Normalizer
+
+	// Encoder, if set, expands the normalized inputs into the features of
+	// the first layer. Set it with SetEncoder. It is saved with the model.
+	Encoder *Encoder
+
+	// ws is the workspace behind the Network's own methods. It shares its
+	// buffers with the layers, InputMatrix and ExpectedMatrix.
+	ws *Workspace
 }
 type NetworkData struct {
-	Weights [][][]float64
-	Biases  [][]float64
+	Weights    [][][]float64
+	Biases     [][]float64
+	Classes    []string    `json:",omitempty"`
+	Normalizer *Normalizer `json:",omitempty"`
+	Encoder    *Encoder    `json:",omitempty"`
 }
 
 func NewNetwork(layerSizes []int) *Network {
@@ -36,76 +56,209 @@ func NewNetwork(layerSizes []int) *Network {
 	inputSize := layerSizes[0]
 	outputSize := layerSizes[len(layerSizes)-1]
 
-	return &Network{
+	n := &Network{
 		Layers:         layers,
 		InputMatrix:    matrix.NewMatrix(1, inputSize, make([]float64, inputSize)),
 		ExpectedMatrix: matrix.NewMatrix(1, outputSize, make([]float64, outputSize)),
 	}
+	n.ws = &Workspace{
+		layers:   make([]*layerBuffers, len(layers)),
+		input:    n.InputMatrix,
+		expected: n.ExpectedMatrix,
+		label:    matrix.NewMatrix(1, outputSize, make([]float64, outputSize)),
+	}
+	for i, layer := range layers {
+		n.ws.layers[i] = layer.buffers
+	}
+	return n
 }
 
-func (n *Network) Forward(inputs *[]float64) []float64 {
-	if len(*inputs) != n.Layers[0].numInputs {
-		panic("Input size does not match the number of inputs of the first layer")
+// Forward runs inputs through the network and returns the output in a newly
+// allocated slice. The inputs are copied, so the caller may reuse them.
+func (n *Network) Forward(inputs []float64) ([]float64, error) {
+	out, err := n.forward(n.ws, inputs)
+	if err != nil {
+		return nil, err
 	}
+	return append([]float64(nil), out...), nil
+}
 
-	n.InputMatrix.Data = *inputs
-	currentInputsMatrix := n.InputMatrix
+// ForwardInto is Forward writing the output into dst, which must be as long
+// as the output layer, to avoid an allocation per call.
+func (n *Network) ForwardInto(dst, inputs []float64) error {
+	if len(dst) != n.Layers[len(n.Layers)-1].numNeurons {
+		return errors.New("Destination size does not match the output layer")
+	}
+	out, err := n.forward(n.ws, inputs)
+	if err != nil {
+		return err
+	}
+	copy(dst, out)
+	return nil
+}
 
-	for _, layer := range n.Layers {
-		currentInputsMatrix = layer.Forward(currentInputsMatrix)
+// ForwardSparse runs a forward pass on a single CSR input row.
+func (n *Network) ForwardSparse(inputs *matrix.SparseMatrix) ([]float64, error) {
+	out, err := n.forwardSparse(n.ws, inputs)
+	if err != nil {
+		return nil, err
 	}
+	return append([]float64(nil), out...), nil
+}
 
-	return currentInputsMatrix.Data
+// Backward backpropagates the error against expected for the last Forward
+// call. The expected values are copied.
+func (n *Network) Backward(expected []float64) error {
+	return n.backward(n.ws, expected)
 }
 
-func (n *Network) Backward(expected *[]float64) {
-	n.ExpectedMatrix.Data = *expected
-	expectedMatrix := n.ExpectedMatrix
-
-	for i := len(n.Layers) - 1; i >= 0; i-- {
-		layer := n.Layers[i]
-		if i == len(n.Layers)-1 {
-			// For the output layer
-			matrix.Subtract(layer.Output, expectedMatrix, layer.errorMatrix)
-			matrix.ApplyFunction(layer.Output, layer.activationDerivative, layer.derivativeMatrix)
-			matrix.MultiplyElementWise(layer.errorMatrix, layer.derivativeMatrix, layer.Deltas)
-		} else {
-			// For hidden layers
-			nextLayer := n.Layers[i+1]
-			weightsT := matrix.Transpose(nextLayer.Weights)
-			matrix.DotProduct(nextLayer.Deltas, weightsT, layer.errorMatrix)
-			matrix.ApplyFunction(layer.Output, layer.activationDerivative, layer.derivativeMatrix)
-			matrix.MultiplyElementWise(layer.errorMatrix, layer.derivativeMatrix, layer.Deltas)
+// BackwardClass backpropagates against a one-hot target for the given class
+// index, so callers can keep labels as ints instead of dense vectors.
+func (n *Network) BackwardClass(class int) error {
+	return n.backwardClass(n.ws, class)
+}
+
+func (n *Network) Update(learningRate float64) error {
+	return n.update(n.ws, learningRate)
+}
+
+func (n *Network) AccumulateGradients() error {
+	return n.accumulateGradients(n.ws)
+}
+
+func (n *Network) ApplyGradients(learningRate float64, batchSize int) error {
+	for i, layer := range n.Layers {
+		if err := layer.ApplyGradients(learningRate, batchSize); err != nil {
+			for _, rest := range n.Layers[i+1:] {
+				rest.ResetGradients()
+			}
+			return err
 		}
 	}
+	return nil
 }
 
-func (n *Network) Update(learningRate float64) {
+// TrainBatch takes one gradient step on the mean gradient of the batch.
+func (n *Network) TrainBatch(inputs, expected [][]float64, learningRate float64) error {
+	if len(inputs) != len(expected) {
+		return errors.New("Inputs and expected outputs have different lengths")
+	}
+	for i := range inputs {
+		if _, err := n.forward(n.ws, inputs[i]); err != nil {
+			n.resetGradients()
+			return err
+		}
+		if err := n.backward(n.ws, expected[i]); err != nil {
+			n.resetGradients()
+			return err
+		}
+		if err := n.AccumulateGradients(); err != nil {
+			n.resetGradients()
+			return err
+		}
+	}
+	return n.ApplyGradients(learningRate, len(inputs))
+}
+
+func (n *Network) TrainBatchClasses(inputs [][]float64, labels []int, learningRate float64) error {
+	if len(inputs) != len(labels) {
+		return errors.New("Inputs and labels have different lengths")
+	}
+	for i := range inputs {
+		if _, err := n.forward(n.ws, inputs[i]); err != nil {
+			n.resetGradients()
+			return err
+		}
+		if err := n.backwardClass(n.ws, labels[i]); err != nil {
+			n.resetGradients()
+			return err
+		}
+		if err := n.AccumulateGradients(); err != nil {
+			n.resetGradients()
+			return err
+		}
+	}
+	return n.ApplyGradients(learningRate, len(inputs))
+}
+
+func (n *Network) resetGradients() {
 	for _, layer := range n.Layers {
-		layer.Update(learningRate)
+		layer.ResetGradients()
+	}
+}
+
+func (n *Network) Train(inputs, expected []float64, learningRate float64) error {
+	if _, err := n.forward(n.ws, inputs); err != nil {
+		return err
+	}
+	if err := n.backward(n.ws, expected); err != nil {
+		return err
+	}
+	return n.Update(learningRate)
+}
+
+func (n *Network) TrainClass(inputs []float64, class int, learningRate float64) error {
+	if _, err := n.forward(n.ws, inputs); err != nil {
+		return err
+	}
+	if err := n.backwardClass(n.ws, class); err != nil {
+		return err
+	}
+	return n.Update(learningRate)
+}
+
+func (n *Network) TrainSparse(inputs *matrix.SparseMatrix, class int, learningRate float64) error {
+	if _, err := n.forwardSparse(n.ws, inputs); err != nil {
+		return err
+	}
+	if err := n.backwardClass(n.ws, class); err != nil {
+		return err
+	}
+	return n.Update(learningRate)
+}
+
+func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, epoch int) error {
+	if len(input) != len(expected) {
+		return errors.New("Inputs and expected outputs have different lengths")
 	}
+	return trainLoop(len(input), epoch, func(j int) error {
+		return n.Train(input[j], expected[j], learningRate)
+	})
 }
 
-func (n *Network) Train(inputs, expected *[]float64, learningRate float64) {
-	n.Forward(inputs)
-	n.Backward(expected)
-	n.Update(learningRate)
+// TrainLoopClasses is TrainLoop with integer class labels in place of
+// one-hot expected vectors.
+func (n *Network) TrainLoopClasses(input [][]float64, labels []int, learningRate float64, epoch int) error {
+	if len(input) != len(labels) {
+		return errors.New("Inputs and labels have different lengths")
+	}
+	return trainLoop(len(input), epoch, func(j int) error {
+		return n.TrainClass(input[j], labels[j], learningRate)
+	})
 }
 
-func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, epoch int) {
+// trainLoop drives step over every sample index for the given number of
+// epochs while drawing the progress bars. It stops at the first error.
+func trainLoop(numSamples, epoch int, step func(j int) error) error {
 	const barWidth = 50
 	startTime := time.Now()
-	totalSamples := len(input) * epoch
+	totalSamples := numSamples * epoch
+	barInterval := numSamples / 100
+	if barInterval == 0 {
+		barInterval = 1
+	}
 
 	// Initial print of epoch bar and sample bar
 	fmt.Printf("Epoch: [%s] %.2f%% (%d/%d) - Speed: -- samples/s - Time Left: --:--:--\n", strings.Repeat(" ", barWidth), 0.0, 0, epoch)
-	fmt.Printf("Sample: [%s] %.2f%% (%d/%d)\n", strings.Repeat(" ", barWidth), 0.0, 0, len(input))
+	fmt.Printf("Sample: [%s] %.2f%% (%d/%d)\n", strings.Repeat(" ", barWidth), 0.0, 0, numSamples)
 
 	for i := 0; i < epoch; i++ {
-		for j, in := range input {
-			n.Train(&in, &expected[j], learningRate)
+		for j := 0; j < numSamples; j++ {
+			if err := step(j); err != nil {
+				return err
+			}
 
-			currentSample := i*len(input) + j + 1
+			currentSample := i*numSamples + j + 1
 
 			// Calculate time left and speed
 			elapsedTime := time.Since(startTime)
@@ -119,7 +272,7 @@ func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, e
 			}
 
 			// Update sample progress bar
-			if (j+1)%(len(input)/100) == 0 || j == len(input)-1 { // Update sample bar at ~1% intervals
+			if (j+1)%barInterval == 0 || j == numSamples-1 { // Update sample bar at ~1% intervals
 				// Move cursor up 2 lines, clear both lines, then redraw
 				fmt.Print("\033[2A\033[K")
 
@@ -129,9 +282,9 @@ func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, e
 			fmt.Printf("\rEpoch: [%s] %.2f%% (%d/%d) - Speed: %.2f samples/s - Time Left: %s\n", epochBar, epochProgress*100, i+1, epoch, speed, timeLeft.Round(time.Second))
 
 				// Redraw sample bar
-			sampleProgress := float64(j+1) / float64(len(input))
+			sampleProgress := float64(j+1) / float64(numSamples)
 			sampleBar := strings.Repeat("=", int(sampleProgress*barWidth)) + strings.Repeat(" ", barWidth-int(sampleProgress*barWidth))
-			fmt.Printf("\rSample: [%s] %.2f%% (%d/%d)\n", sampleBar, sampleProgress*100, j+1, len(input))
+			fmt.Printf("\rSample: [%s] %.2f%% (%d/%d)\n", sampleBar, sampleProgress*100, j+1, numSamples)
 			}
 		}
 		// After each epoch, update epoch bar to reflect completion of current epoch
@@ -144,7 +297,7 @@ func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, e
 		elapsedTime := time.Since(startTime)
 		var timeLeft time.Duration = 0
 		var speed float64 = 0.0
-		currentSample := (i + 1) * len(input) // Total samples processed up to the end of this epoch
+		currentSample := (i + 1) * numSamples // Total samples processed up to the end of this epoch
 		if currentSample > 0 {
 			timePerSample := float64(elapsedTime) / float64(currentSample)
 			remainingSamples := totalSamples - currentSample
@@ -153,12 +306,32 @@ func (n *Network) TrainLoop(input, expected [][]float64, learningRate float64, e
 		}
 
 		fmt.Printf("\rEpoch: [%s] %.2f%% (%d/%d) - Speed: %.2f samples/s - Time Left: %s\n", epochBar, epochProgress*100, i+1, epoch, speed, timeLeft.Round(time.Second))
-		fmt.Printf("\rSample: [%s] 100.00%% (%d/%d)\n", strings.Repeat("=", barWidth), len(input), len(input))
+		fmt.Printf("\rSample: [%s] 100.00%% (%d/%d)\n", strings.Repeat("=", barWidth), numSamples, numSamples)
 	}
 	// Final epoch bar (100%)
 	fmt.Print("\033[2A\033[K")
 	fmt.Printf("\rEpoch: [%s] 100.00%% (%d/%d) - Speed: %.2f samples/s - Time Left: 0s\n", strings.Repeat("=", barWidth), epoch, epoch, float64(totalSamples)/time.Since(startTime).Seconds())
-	fmt.Printf("\rSample: [%s] 100.00%% (%d/%d)\n", strings.Repeat("=", barWidth), len(input), len(input))
+	fmt.Printf("\rSample: [%s] 100.00%% (%d/%d)\n", strings.Repeat("=", barWidth), numSamples, numSamples)
+	return nil
+}
+
+// Clone returns an independent copy of the network with the same weights.
+func (n *Network) Clone() *Network {
+	c := NewNetwork(n.GetLayerSizes())
+	c.CopyWeightsFrom(n)
+	c.Classes = append([]string(nil), n.Classes...)
+	c.Normalizer = n.Normalizer.clone()
+	c.Encoder = n.Encoder.clone()
+	return c
+}
+
+// CopyWeightsFrom overwrites the weights and biases with those of src, which
+// must have the same layer sizes.
+func (n *Network) CopyWeightsFrom(src *Network) {
+	for i, layer := range n.Layers {
+		copy(layer.Weights.Data, src.Layers[i].Weights.Data)
+		copy(layer.Biases.Data, src.Layers[i].Biases.Data)
+	}
 }
 
 func (n *Network) GetLayerSizes() []int {
@@ -213,8 +386,11 @@ func (n *Network) setBiases(biases [][]float64) {
 
 func (n *Network) Save(path string) error {
 	data := NetworkData{
-		Weights: n.getWeights(),
-		Biases:  n.getBiases(),
+		Weights:    n.getWeights(),
+		Biases:     n.getBiases(),
+		Classes:    n.Classes,
+		Normalizer: n.Normalizer,
+		Encoder:    n.Encoder,
 	}
 
 	file, err := json.MarshalIndent(data, "", " ")
@@ -246,6 +422,10 @@ func Load(path string) (*Network, error) {
 	n := NewNetwork(layerSizes)
 	n.setWeights(data.Weights)
 	n.setBiases(data.Biases)
+	n.Classes = data.Classes
+	if err := n.setInputTransforms(data.Normalizer, data.Encoder); err != nil {
+		return nil, err
+	}
 
 	return n, nil
 }
@@ -264,6 +444,7 @@ func (n *Network) Load(path string) error {
 
 	n.setWeights(data.Weights)
 	n.setBiases(data.Biases)
+	n.Classes = data.Classes
 
-	return nil
+	return n.setInputTransforms(data.Normalizer, data.Encoder)
 }
\ No newline at end of file
@@ -0,0 +1,124 @@
+package network
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSizeErrors(t *testing.T) {
+	base := NewNetwork([]int{2, 4, 3})
+	n := base.Clone()
+
+	if _, err := n.Forward([]float64{1, 2, 3}); err == nil {
+		t.Error("Forward accepted 3 inputs for 2")
+	}
+	if _, err := n.Forward(nil); err == nil {
+		t.Error("Forward accepted no inputs")
+	}
+	if err := n.ForwardInto(make([]float64, 2), []float64{1, 2}); err == nil {
+		t.Error("ForwardInto accepted a destination of 2 for 3 outputs")
+	}
+	if _, err := n.Forward([]float64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Backward([]float64{1, 0}); err == nil {
+		t.Error("Backward accepted 2 targets for 3 outputs")
+	}
+	if err := n.BackwardClass(3); err == nil {
+		t.Error("BackwardClass accepted class 3 of 3")
+	}
+	if err := n.BackwardClass(-1); err == nil {
+		t.Error("BackwardClass accepted class -1")
+	}
+	if err := n.Train([]float64{1}, []float64{1, 0, 0}, 0.1); err == nil {
+		t.Error("Train accepted 1 input for 2")
+	}
+	if err := n.Train([]float64{1, 2}, []float64{1, 0, 0, 0}, 0.1); err == nil {
+		t.Error("Train accepted 4 targets for 3")
+	}
+	if err := n.TrainClass([]float64{1, 2}, 5, 0.1); err == nil {
+		t.Error("TrainClass accepted class 5 of 3")
+	}
+	if err := n.TrainBatch([][]float64{{1, 2}, {3, 4}}, [][]float64{{1, 0, 0}}, 0.1); err == nil {
+		t.Error("TrainBatch accepted 2 inputs and 1 target")
+	}
+	if err := n.TrainBatch([][]float64{{1, 2}, {3, 4}}, [][]float64{{1, 0, 0}, {1, 0}}, 0.1); err == nil {
+		t.Error("TrainBatch accepted a short target")
+	}
+	if err := n.TrainBatchClasses([][]float64{{1, 2}, {3}}, []int{0, 1}, 0.1); err == nil {
+		t.Error("TrainBatchClasses accepted a short input")
+	}
+	// None of the failed calls may have changed the weights.
+	assertSameWeights(t, n, base, 0)
+
+	// Nor left gradients behind for the next batch.
+	want := base.Clone()
+	inputs, expected := [][]float64{{1, 2}, {3, 4}}, [][]float64{{1, 0, 0}, {0, 1, 0}}
+	if err := want.TrainBatch(inputs, expected, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.TrainBatch(inputs, expected, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	assertSameWeights(t, n, want, 0)
+}
+
+func TestForwardReturnsACopy(t *testing.T) {
+	n := NewNetwork([]int{2, 4, 3})
+	inputs := []float64{0.5, -0.25}
+	first, err := n.Forward(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := slices.Clone(first)
+	for i := range first {
+		first[i] = 42
+	}
+	if slices.Contains(n.Layers[len(n.Layers)-1].buffers.output.Data, 42) {
+		t.Fatal("changing Forward's result changed the output layer")
+	}
+	second, err := n.Forward(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(second, want) {
+		t.Fatalf("second Forward = %v, want %v", second, want)
+	}
+	if &second[0] == &first[0] {
+		t.Fatal("Forward returned the same slice twice")
+	}
+}
+
+func TestCallerSlicesAreCopied(t *testing.T) {
+	base := NewNetwork([]int{2, 4, 3})
+	inputs := []float64{0.5, -0.25}
+	expected := []float64{0, 1, 0}
+
+	want := base.Clone()
+	if err := want.Train(slices.Clone(inputs), slices.Clone(expected), 0.1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the caller's slices between the steps of a training pass
+	// must not reach the network.
+	n := base.Clone()
+	x, y := slices.Clone(inputs), slices.Clone(expected)
+	if _, err := n.Forward(x); err != nil {
+		t.Fatal(err)
+	}
+	x[0], x[1] = 100, 100
+	if !slices.Equal(n.InputMatrix.Data, inputs) {
+		t.Fatalf("input matrix = %v after changing the caller's inputs, want %v", n.InputMatrix.Data, inputs)
+	}
+	if err := n.Backward(y); err != nil {
+		t.Fatal(err)
+	}
+	y[0], y[1], y[2] = 5, 5, 5
+	if !slices.Equal(n.ExpectedMatrix.Data, expected) {
+		t.Fatalf("expected matrix = %v after changing the caller's targets, want %v", n.ExpectedMatrix.Data, expected)
+	}
+	if err := n.Update(0.1); err != nil {
+		t.Fatal(err)
+	}
+	assertSameWeights(t, n, want, 0)
+}
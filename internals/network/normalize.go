@@ -0,0 +1,162 @@
+package network
+
+import (
+	"errors"
+	"math"
+)
+
+// Normalizer kinds.
+const (
+	NormalizeMinMax   = "minmax"
+	NormalizeStandard = "standard"
+	NormalizeBounds   = "bounds"
+)
+
+// Normalizer is an affine per-input transform, (x - Offset) * Scale, applied
+// to every input before the first layer. It is saved with the model so that
+// predictions see inputs scaled exactly as in training.
+type Normalizer struct {
+	// Kind records how the parameters were derived.
+	Kind   string
+	Offset []float64
+	Scale  []float64
+}
+
+// NewMinMaxNormalizer maps the range of every input column in inputs onto
+// [-1, 1].
+func NewMinMaxNormalizer(inputs [][]float64) (*Normalizer, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("Cannot fit a normalizer to no inputs")
+	}
+	lower := append([]float64(nil), inputs[0]...)
+	upper := append([]float64(nil), inputs[0]...)
+	for _, in := range inputs[1:] {
+		if len(in) != len(lower) {
+			return nil, errors.New("Inputs have different lengths")
+		}
+		for j, v := range in {
+			lower[j] = min(lower[j], v)
+			upper[j] = max(upper[j], v)
+		}
+	}
+	z, err := NewBoundsNormalizer(lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	z.Kind = NormalizeMinMax
+	return z, nil
+}
+
+// NewBoundsNormalizer maps [lower[j], upper[j]] onto [-1, 1] for every input
+// j, e.g. the bounding box of a map. Inputs with an empty range are only
+// centred.
+func NewBoundsNormalizer(lower, upper []float64) (*Normalizer, error) {
+	if len(lower) != len(upper) {
+		return nil, errors.New("Bounds have different lengths")
+	}
+	z := &Normalizer{
+		Kind:   NormalizeBounds,
+		Offset: make([]float64, len(lower)),
+		Scale:  make([]float64, len(lower)),
+	}
+	for j := range lower {
+		z.Offset[j] = (lower[j] + upper[j]) / 2
+		z.Scale[j] = 1
+		if span := upper[j] - lower[j]; span > 0 {
+			z.Scale[j] = 2 / span
+		}
+	}
+	return z, nil
+}
+
+// NewStandardNormalizer shifts every input column to zero mean and unit
+// population standard deviation. Constant columns are only centred.
+func NewStandardNormalizer(inputs [][]float64) (*Normalizer, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("Cannot fit a normalizer to no inputs")
+	}
+	size := len(inputs[0])
+	z := &Normalizer{
+		Kind:   NormalizeStandard,
+		Offset: make([]float64, size),
+		Scale:  make([]float64, size),
+	}
+	for _, in := range inputs {
+		if len(in) != size {
+			return nil, errors.New("Inputs have different lengths")
+		}
+		for j, v := range in {
+			z.Offset[j] += v
+		}
+	}
+	for j := range z.Offset {
+		z.Offset[j] /= float64(len(inputs))
+	}
+	for _, in := range inputs {
+		for j, v := range in {
+			d := v - z.Offset[j]
+			z.Scale[j] += d * d
+		}
+	}
+	for j := range z.Scale {
+		std := math.Sqrt(z.Scale[j] / float64(len(inputs)))
+		z.Scale[j] = 1
+		if std > 0 {
+			z.Scale[j] = 1 / std
+		}
+	}
+	return z, nil
+}
+
+// Size is the number of inputs the normalizer applies to.
+func (z *Normalizer) Size() int {
+	return len(z.Offset)
+}
+
+// Apply writes the normalized src into dst. Both must have Size elements.
+func (z *Normalizer) Apply(dst, src []float64) {
+	for j, v := range src {
+		dst[j] = (v - z.Offset[j]) * z.Scale[j]
+	}
+}
+
+func (z *Normalizer) clone() *Normalizer {
+	if z == nil {
+		return nil
+	}
+	return &Normalizer{
+		Kind:   z.Kind,
+		Offset: append([]float64(nil), z.Offset...),
+		Scale:  append([]float64(nil), z.Scale...),
+	}
+}
+
+// SetNormalizer installs z, or removes normalization when z is nil.
+func (n *Network) SetNormalizer(z *Normalizer) error {
+	if z != nil && (z.Size() != n.InputSize() || len(z.Scale) != z.Size()) {
+		return errors.New("Normalizer size does not match the number of inputs of the network")
+	}
+	n.Normalizer = z
+	return nil
+}
+
+// loadInputs writes the first layer's inputs for inputs into dst: inputs
+// normalized and encoded by the network's normalizer and encoder, if any.
+// scratch holds the normalized inputs for the encoder and must have
+// InputSize elements.
+func (n *Network) loadInputs(dst, scratch, inputs []float64) {
+	if n.Encoder == nil {
+		if n.Normalizer != nil {
+			n.Normalizer.Apply(dst, inputs)
+			return
+		}
+		copy(dst, inputs)
+		return
+	}
+	normalized := inputs
+	if n.Normalizer != nil {
+		n.Normalizer.Apply(scratch, inputs)
+		normalized = scratch
+	}
+	n.Encoder.Apply(dst, inputs, normalized)
+}
@@ -0,0 +1,146 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/whyisemerald/neural_network/internals/matrix"
+)
+
+// DataParallelTrainer trains a network on mini-batches split across worker
+// replicas. Each replica computes the gradients of its shard of the batch;
+// the gradients are then summed into the master network and applied once,
+// so a step matches Network.TrainBatch up to floating point summation order.
+// The shards run on matrix.Pool(), like the matrix operations themselves.
+type DataParallelTrainer struct {
+	Network  *Network
+	replicas []*Network
+}
+
+func NewDataParallelTrainer(n *Network, workers int) *DataParallelTrainer {
+	if workers < 1 {
+		workers = 1
+	}
+	replicas := make([]*Network, workers)
+	for i := range replicas {
+		replicas[i] = n.Clone()
+	}
+	return &DataParallelTrainer{
+		Network:  n,
+		replicas: replicas,
+	}
+}
+
+func (t *DataParallelTrainer) Workers() int {
+	return len(t.replicas)
+}
+
+// TrainBatch takes one step on the mean gradient of the batch.
+func (t *DataParallelTrainer) TrainBatch(inputs, expected [][]float64, learningRate float64) error {
+	if len(inputs) != len(expected) {
+		return errors.New("Inputs and expected outputs have different lengths")
+	}
+	return t.step(len(inputs), learningRate, func(r *Network, j int) error {
+		if _, err := r.forward(r.ws, inputs[j]); err != nil {
+			return err
+		}
+		return r.backward(r.ws, expected[j])
+	})
+}
+
+// TrainBatchClasses is TrainBatch with integer class labels.
+func (t *DataParallelTrainer) TrainBatchClasses(inputs [][]float64, labels []int, learningRate float64) error {
+	if len(inputs) != len(labels) {
+		return errors.New("Inputs and labels have different lengths")
+	}
+	return t.step(len(inputs), learningRate, func(r *Network, j int) error {
+		if _, err := r.forward(r.ws, inputs[j]); err != nil {
+			return err
+		}
+		return r.backwardClass(r.ws, labels[j])
+	})
+}
+
+// TrainLoop runs the given number of epochs over the data in batches of
+// batchSize samples.
+func (t *DataParallelTrainer) TrainLoop(inputs, expected [][]float64, learningRate float64, epoch, batchSize int) error {
+	return t.loop(len(inputs), epoch, batchSize, func(start, end int) error {
+		return t.TrainBatch(inputs[start:end], expected[start:end], learningRate)
+	})
+}
+
+func (t *DataParallelTrainer) TrainLoopClasses(inputs [][]float64, labels []int, learningRate float64, epoch, batchSize int) error {
+	return t.loop(len(inputs), epoch, batchSize, func(start, end int) error {
+		return t.TrainBatchClasses(inputs[start:end], labels[start:end], learningRate)
+	})
+}
+
+func (t *DataParallelTrainer) loop(numSamples, epoch, batchSize int, batch func(start, end int) error) error {
+	if batchSize < 1 {
+		return errors.New("Batch size must be positive")
+	}
+	numBatches := (numSamples + batchSize - 1) / batchSize
+	return trainLoop(numBatches, epoch, func(b int) error {
+		return batch(b*batchSize, min((b+1)*batchSize, numSamples))
+	})
+}
+
+// step syncs the replicas with the master weights and input transforms, lets
+// each one accumulate gradients over its shard of [0, batchSize) and then
+// all-reduces the sums into the master before applying them.
+func (t *DataParallelTrainer) step(batchSize int, learningRate float64, sample func(r *Network, j int) error) error {
+	if batchSize == 0 {
+		return nil
+	}
+	shard := (batchSize + len(t.replicas) - 1) / len(t.replicas)
+
+	group := matrix.Pool().NewNamedGroup("network.DataParallelTrainer")
+	for i, replica := range t.replicas {
+		start := i * shard
+		end := min(start+shard, batchSize)
+		if start >= end {
+			break
+		}
+		group.Go(func() error {
+			replica.CopyWeightsFrom(t.Network)
+			replica.Normalizer = t.Network.Normalizer
+			replica.Encoder = t.Network.Encoder
+			for j := start; j < end; j++ {
+				if err := sample(replica, j); err != nil {
+					return err
+				}
+				if err := replica.AccumulateGradients(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		t.resetGradients()
+		return err
+	}
+
+	for _, replica := range t.replicas {
+		for l, layer := range t.Network.Layers {
+			rl := replica.Layers[l]
+			if err := matrix.Add(layer.weightGradientSum, rl.weightGradientSum, layer.weightGradientSum); err != nil {
+				t.resetGradients()
+				return err
+			}
+			if err := matrix.Add(layer.biasGradientSum, rl.biasGradientSum, layer.biasGradientSum); err != nil {
+				t.resetGradients()
+				return err
+			}
+			rl.ResetGradients()
+		}
+	}
+	return t.Network.ApplyGradients(learningRate, batchSize)
+}
+
+// resetGradients clears the sums of the master and of every replica.
+func (t *DataParallelTrainer) resetGradients() {
+	t.Network.resetGradients()
+	for _, replica := range t.replicas {
+		replica.resetGradients()
+	}
+}
@@ -0,0 +1,125 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/whyisemerald/neural_network/internals/matrix"
+	"github.com/whyisemerald/neural_network/internals/routines"
+)
+
+// randomSamples returns n inputs of the given size with values in [-1, 1),
+// one-hot targets over classes outputs and the matching labels.
+func randomSamples(n, inputs, classes int, rng *rand.Rand) ([][]float64, [][]float64, []int) {
+	x := make([][]float64, n)
+	y := make([][]float64, n)
+	labels := make([]int, n)
+	for i := range x {
+		x[i] = make([]float64, inputs)
+		for j := range x[i] {
+			x[i][j] = 2*rng.Float64() - 1
+		}
+		labels[i] = rng.Intn(classes)
+		y[i] = make([]float64, classes)
+		y[i][labels[i]] = 1
+	}
+	return x, y, labels
+}
+
+func assertSameWeights(t *testing.T, got, want *Network, tol float64) {
+	t.Helper()
+	for l := range want.Layers {
+		for name, pair := range map[string][2]*matrix.Matrix{
+			"weights": {got.Layers[l].Weights, want.Layers[l].Weights},
+			"biases":  {got.Layers[l].Biases, want.Layers[l].Biases},
+		} {
+			for i := range pair[1].Data {
+				if d := math.Abs(pair[0].Data[i] - pair[1].Data[i]); d > tol {
+					t.Fatalf("layer %d %s[%d] = %v, want %v (off by %g)", l, name, i, pair[0].Data[i], pair[1].Data[i], d)
+				}
+			}
+		}
+	}
+}
+
+func TestDataParallelMatchesTrainBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := NewNetwork([]int{3, 8, 6, 4})
+	for _, workers := range []int{1, 2, 3, 4, 8} {
+		for _, batchSize := range []int{1, 2, 5, 17, 32} {
+			t.Run(fmt.Sprintf("workers=%d/batch=%d", workers, batchSize), func(t *testing.T) {
+				inputs, expected, labels := randomSamples(3*batchSize, 3, 4, rng)
+
+				serial := base.Clone()
+				parallel := base.Clone()
+				trainer := NewDataParallelTrainer(parallel, workers)
+				serialClasses := base.Clone()
+				parallelClasses := base.Clone()
+				classTrainer := NewDataParallelTrainer(parallelClasses, workers)
+
+				// Several steps in a row, so the replicas have to pick up the
+				// master's new weights every time.
+				for start := 0; start < len(inputs); start += batchSize {
+					x, y, l := inputs[start:start+batchSize], expected[start:start+batchSize], labels[start:start+batchSize]
+					if err := serial.TrainBatch(x, y, 0.1); err != nil {
+						t.Fatal(err)
+					}
+					if err := trainer.TrainBatch(x, y, 0.1); err != nil {
+						t.Fatal(err)
+					}
+					if err := serialClasses.TrainBatchClasses(x, l, 0.1); err != nil {
+						t.Fatal(err)
+					}
+					if err := classTrainer.TrainBatchClasses(x, l, 0.1); err != nil {
+						t.Fatal(err)
+					}
+				}
+				assertSameWeights(t, parallel, serial, 1e-12)
+				assertSameWeights(t, parallelClasses, serialClasses, 1e-12)
+			})
+		}
+	}
+}
+
+func TestDataParallelErrorsLeaveNoGradients(t *testing.T) {
+	base := NewNetwork([]int{2, 4, 3})
+	n := base.Clone()
+	trainer := NewDataParallelTrainer(n, 2)
+	inputs := [][]float64{{0, 1}, {1, 0}, {1, 1}, {0, 0}}
+	if err := trainer.TrainBatchClasses(inputs, []int{0, 1, 7, 2}, 0.1); err == nil {
+		t.Fatal("TrainBatchClasses accepted an out of range label")
+	}
+	if err := trainer.TrainBatch(inputs, [][]float64{{1, 0, 0}}, 0.1); err == nil {
+		t.Fatal("TrainBatch accepted mismatched lengths")
+	}
+	assertSameWeights(t, n, base, 0)
+
+	// The failed steps must not leak gradients into the next one.
+	serial := base.Clone()
+	labels := []int{0, 1, 2, 0}
+	if err := serial.TrainBatchClasses(inputs, labels, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if err := trainer.TrainBatchClasses(inputs, labels, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	assertSameWeights(t, n, serial, 1e-12)
+}
+
+func TestDataParallelUsesMatrixPool(t *testing.T) {
+	p := routines.NewPool(routines.WithWorkers(4))
+	defer p.Close()
+	matrix.SetPool(p)
+	defer matrix.SetPool(nil)
+
+	inputs, expected, _ := randomSamples(32, 3, 4, rand.New(rand.NewSource(2)))
+	trainer := NewDataParallelTrainer(NewNetwork([]int{3, 8, 4}), 4)
+	if err := trainer.TrainBatch(inputs, expected, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if p.Stats().Submitted == 0 {
+		t.Fatal("no shard ran on the pool set with matrix.SetPool")
+	}
+}
@@ -0,0 +1,81 @@
+package network
+
+import (
+	"errors"
+	"sync"
+)
+
+// Predictor runs forward passes on a network from any number of goroutines.
+// The weights are shared and only read; every call borrows a Workspace from
+// a sync.Pool, so concurrent predictions never see each other's buffers.
+// The network must not be trained while a Predictor is in use.
+type Predictor struct {
+	network    *Network
+	workspaces sync.Pool
+}
+
+func (n *Network) NewPredictor() *Predictor {
+	p := &Predictor{network: n}
+	p.workspaces.New = func() any {
+		return n.NewWorkspace()
+	}
+	return p
+}
+
+func (p *Predictor) Network() *Network {
+	return p.network
+}
+
+// Predict returns the network output for inputs in a newly allocated slice.
+func (p *Predictor) Predict(inputs []float64) ([]float64, error) {
+	out := make([]float64, p.network.Layers[len(p.network.Layers)-1].numNeurons)
+	if err := p.PredictInto(out, inputs); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PredictInto writes the network output for inputs into dst, which must be
+// as long as the output layer.
+func (p *Predictor) PredictInto(dst, inputs []float64) error {
+	n := p.network
+	if len(dst) != n.Layers[len(n.Layers)-1].numNeurons {
+		return errors.New("Destination size does not match the output layer")
+	}
+
+	ws := p.workspaces.Get().(*Workspace)
+	defer p.workspaces.Put(ws)
+
+	out, err := n.forward(ws, inputs)
+	if err != nil {
+		return err
+	}
+	copy(dst, out)
+	return nil
+}
+
+// PredictClass returns the index of the largest output for inputs.
+func (p *Predictor) PredictClass(inputs []float64) (int, error) {
+	class, _, err := p.Classify(inputs)
+	return class, err
+}
+
+// Classify returns the index of the largest output for inputs together with
+// the outputs themselves.
+func (p *Predictor) Classify(inputs []float64) (int, []float64, error) {
+	out, err := p.Predict(inputs)
+	if err != nil {
+		return -1, nil, err
+	}
+	return argmax(out), out, nil
+}
+
+func argmax(values []float64) int {
+	best := -1
+	for i, v := range values {
+		if best == -1 || v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
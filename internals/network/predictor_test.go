@@ -0,0 +1,96 @@
+package network
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// encodedNetwork returns a classifier over longitude and latitude with a
+// standard normalizer and a fourier and sphere encoder, as nn train builds
+// them, along with the inputs the normalizer was fitted to.
+func encodedNetwork(t *testing.T, rng *rand.Rand) (*Network, [][]float64) {
+	t.Helper()
+	inputs := make([][]float64, 200)
+	for i := range inputs {
+		inputs[i] = []float64{68 + 30*rng.Float64(), 8 + 29*rng.Float64()}
+	}
+	e, err := NewFourierEncoder(2, 4, 2, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Sphere = true
+	z, err := NewStandardNormalizer(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNetwork([]int{e.Size(), 8, 5})
+	if err := n.SetEncoder(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.SetNormalizer(z); err != nil {
+		t.Fatal(err)
+	}
+	return n, inputs
+}
+
+func TestPredictorConcurrentClassify(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	plain := NewNetwork([]int{2, 8, 5})
+	encoded, inputs := encodedNetwork(t, rng)
+
+	for name, n := range map[string]*Network{"plain": plain, "encoded": encoded} {
+		t.Run(name, func(t *testing.T) {
+			want := make([][]float64, len(inputs))
+			for i, x := range inputs {
+				out, err := n.Forward(x)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want[i] = out
+			}
+
+			p := n.NewPredictor()
+			var wg sync.WaitGroup
+			for g := 0; g < 16; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// Every goroutine walks the inputs from a different
+					// offset so that the passes really interleave.
+					for k := range inputs {
+						i := (k + g*len(inputs)/16) % len(inputs)
+						class, out, err := p.Classify(inputs[i])
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if !slices.Equal(out, want[i]) {
+							t.Errorf("input %d: Classify outputs %v, Forward %v", i, out, want[i])
+							return
+						}
+						if class != argmax(want[i]) {
+							t.Errorf("input %d: Classify class %d, want %d", i, class, argmax(want[i]))
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestPredictorErrors(t *testing.T) {
+	p := NewNetwork([]int{2, 4, 3}).NewPredictor()
+	if _, _, err := p.Classify([]float64{1, 2, 3}); err == nil {
+		t.Error("Classify accepted 3 inputs for a 2 input network")
+	}
+	if _, err := p.PredictClass(nil); err == nil {
+		t.Error("PredictClass accepted no inputs")
+	}
+	if err := p.PredictInto(make([]float64, 2), []float64{1, 2}); err == nil {
+		t.Error("PredictInto accepted a destination shorter than the output layer")
+	}
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/whyisemerald/neural_network/internals/matrix"
+)
+
+// Workspace holds the activation buffers of one forward and backward pass
+// through a network. Goroutines that share a network's weights each need
+// their own workspace; the Network methods use one owned by the network.
+type Workspace struct {
+	layers   []*layerBuffers
+	input    *matrix.Matrix
+	expected *matrix.Matrix
+
+	// label holds the one-hot target built from a class index.
+	label *matrix.Matrix
+
+	// normalized holds the normalized inputs ahead of the encoder.
+	normalized []float64
+}
+
+// NewWorkspace allocates a private set of buffers for n.
+func (n *Network) NewWorkspace() *Workspace {
+	inputSize := n.Layers[0].numInputs
+	outputSize := n.Layers[len(n.Layers)-1].numNeurons
+	ws := &Workspace{
+		layers:   make([]*layerBuffers, len(n.Layers)),
+		input:    matrix.NewMatrix(1, inputSize, make([]float64, inputSize)),
+		expected: matrix.NewMatrix(1, outputSize, make([]float64, outputSize)),
+		label:    matrix.NewMatrix(1, outputSize, make([]float64, outputSize)),
+	}
+	for i, layer := range n.Layers {
+		ws.layers[i] = layer.newBuffers()
+	}
+	return ws
+}
+
+// forward copies inputs into the workspace, normalizing and encoding them if
+// the network has a normalizer or an encoder, and runs the layers. The
+// result is the output layer's buffer and is overwritten by the next pass.
+func (n *Network) forward(ws *Workspace, inputs []float64) ([]float64, error) {
+	if len(inputs) != n.InputSize() {
+		return nil, errors.New("Input size does not match the number of inputs of the network")
+	}
+
+	if len(ws.normalized) != len(inputs) {
+		ws.normalized = make([]float64, len(inputs))
+	}
+	n.loadInputs(ws.input.Data, ws.normalized, inputs)
+	currentInputsMatrix := ws.input
+
+	for i, layer := range n.Layers {
+		var err error
+		if currentInputsMatrix, err = layer.forward(ws.layers[i], currentInputsMatrix); err != nil {
+			return nil, err
+		}
+	}
+
+	return currentInputsMatrix.Data, nil
+}
+
+func (n *Network) forwardSparse(ws *Workspace, inputs *matrix.SparseMatrix) ([]float64, error) {
+	if inputs.Rows != 1 || inputs.Cols != n.Layers[0].numInputs {
+		return nil, errors.New("Input size does not match the number of inputs of the first layer")
+	}
+	if n.Normalizer != nil || n.Encoder != nil {
+		return nil, errors.New("Sparse inputs cannot be normalized or encoded")
+	}
+
+	currentInputsMatrix, err := n.Layers[0].forwardSparse(ws.layers[0], inputs)
+	if err != nil {
+		return nil, err
+	}
+	for i, layer := range n.Layers[1:] {
+		if currentInputsMatrix, err = layer.forward(ws.layers[i+1], currentInputsMatrix); err != nil {
+			return nil, err
+		}
+	}
+
+	return currentInputsMatrix.Data, nil
+}
+
+func (n *Network) backward(ws *Workspace, expected []float64) error {
+	if len(expected) != len(ws.expected.Data) {
+		return errors.New("Expected size does not match the output layer")
+	}
+	copy(ws.expected.Data, expected)
+	return n.backwardMatrix(ws, ws.expected)
+}
+
+func (n *Network) backwardClass(ws *Workspace, class int) error {
+	if class < 0 || class >= len(ws.label.Data) {
+		return errors.New("Class index out of range for the output layer")
+	}
+	clear(ws.label.Data)
+	ws.label.Data[class] = 1
+	return n.backwardMatrix(ws, ws.label)
+}
+
+func (n *Network) backwardMatrix(ws *Workspace, expectedMatrix *matrix.Matrix) error {
+	for i := len(n.Layers) - 1; i >= 0; i-- {
+		layer := n.Layers[i]
+		b := ws.layers[i]
+		var err error
+		if i == len(n.Layers)-1 {
+			// For the output layer
+			err = matrix.Subtract(b.output, expectedMatrix, b.errorMatrix)
+		} else {
+			// For hidden layers
+			nextLayer := n.Layers[i+1]
+			weightsT := matrix.Transpose(nextLayer.Weights)
+			err = matrix.DotProduct(ws.layers[i+1].deltas, weightsT, b.errorMatrix)
+		}
+		if err != nil {
+			return err
+		}
+		matrix.ApplyFunction(b.output, layer.activationDerivative, b.derivativeMatrix)
+		if err := matrix.MultiplyElementWise(b.errorMatrix, b.derivativeMatrix, b.deltas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) update(ws *Workspace, learningRate float64) error {
+	for i, layer := range n.Layers {
+		if err := layer.update(ws.layers[i], learningRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Network) accumulateGradients(ws *Workspace) error {
+	for i, layer := range n.Layers {
+		if err := layer.accumulateGradients(ws.layers[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
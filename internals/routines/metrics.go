@@ -0,0 +1,123 @@
+package routines
+
+import (
+	"context"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+)
+
+// Hook receives task lifecycle events from a pool. Methods are called on the
+// goroutine submitting or running the task, so they must be fast and safe
+// for concurrent use.
+type Hook interface {
+	// TaskSubmitted is called once a task is accepted. queued is false when
+	// the queue was full and the submitter runs the task itself.
+	TaskSubmitted(pool string, queued bool)
+	// TaskStarted is called when a task begins, with the time it spent
+	// between submission and starting.
+	TaskStarted(pool string, wait time.Duration)
+	// TaskFinished is called when a task returns or panics.
+	TaskFinished(pool string, run time.Duration)
+}
+
+// WithHook installs a hook that is told about every task on the pool.
+func WithHook(h Hook) Option {
+	return func(c *poolConfig) { c.hook = h }
+}
+
+type poolMetrics struct {
+	submitted atomic.Uint64
+	completed atomic.Uint64
+	inline    atomic.Uint64
+	waitNanos atomic.Int64
+	maxWait   atomic.Int64
+	runNanos  atomic.Int64
+}
+
+// Stats is a point in time view of a pool. Counters are cumulative since the
+// pool was created.
+type Stats struct {
+	Name        string
+	Workers     int
+	Busy        int
+	Idle        int
+	QueueLength int
+	Closed      bool
+
+	Submitted uint64
+	Completed uint64
+	// RanInline counts tasks run by the submitter because the queue was
+	// full. A steadily growing value means the pool is saturated.
+	RanInline uint64
+
+	TotalWait time.Duration
+	MaxWait   time.Duration
+	TotalRun  time.Duration
+	// WorkerBusy is the time each current worker has spent running tasks.
+	WorkerBusy []time.Duration
+}
+
+// MeanWait is the average time a task waited before it started.
+func (s Stats) MeanWait() time.Duration {
+	if s.Completed == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Completed)
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	st := Stats{
+		Name:        p.name,
+		Workers:     len(p.workers),
+		QueueLength: len(p.Tasks),
+		Closed:      p.closed,
+		Submitted:   p.metrics.submitted.Load(),
+		Completed:   p.metrics.completed.Load(),
+		RanInline:   p.metrics.inline.Load(),
+		TotalWait:   time.Duration(p.metrics.waitNanos.Load()),
+		MaxWait:     time.Duration(p.metrics.maxWait.Load()),
+		TotalRun:    time.Duration(p.metrics.runNanos.Load()),
+		WorkerBusy:  make([]time.Duration, len(p.workers)),
+	}
+	for i, worker := range p.workers {
+		if worker.IsIdle() {
+			st.Idle++
+		} else {
+			st.Busy++
+		}
+		st.WorkerBusy[i] = worker.BusyTime()
+	}
+	return st
+}
+
+// runTask runs a submitted task, recording its timings and wrapping it in a
+// runtime/trace region so that `go tool trace` shows it under label.
+func (p *Pool) runTask(task func(), label string, wait time.Duration) {
+	p.metrics.waitNanos.Add(int64(wait))
+	for {
+		current := p.metrics.maxWait.Load()
+		if int64(wait) <= current || p.metrics.maxWait.CompareAndSwap(current, int64(wait)) {
+			break
+		}
+	}
+	if p.hook != nil {
+		p.hook.TaskStarted(p.name, wait)
+	}
+
+	start := time.Now()
+	if trace.IsEnabled() {
+		trace.WithRegion(context.Background(), label, task)
+	} else {
+		task()
+	}
+	elapsed := time.Since(start)
+
+	p.metrics.runNanos.Add(int64(elapsed))
+	p.metrics.completed.Add(1)
+	if p.hook != nil {
+		p.hook.TaskFinished(p.name, elapsed)
+	}
+}
@@ -0,0 +1,185 @@
+package routines
+
+import (
+	"bytes"
+	"runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHook counts the events it receives per pool.
+type recordingHook struct {
+	mu                        sync.Mutex
+	queued, inline            map[string]int
+	started, finished         map[string]int
+	negativeWait, negativeRun bool
+}
+
+func newRecordingHook() *recordingHook {
+	return &recordingHook{
+		queued:   map[string]int{},
+		inline:   map[string]int{},
+		started:  map[string]int{},
+		finished: map[string]int{},
+	}
+}
+
+func (h *recordingHook) TaskSubmitted(pool string, queued bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if queued {
+		h.queued[pool]++
+	} else {
+		h.inline[pool]++
+	}
+}
+
+func (h *recordingHook) TaskStarted(pool string, wait time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started[pool]++
+	h.negativeWait = h.negativeWait || wait < 0
+}
+
+func (h *recordingHook) TaskFinished(pool string, run time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.finished[pool]++
+	h.negativeRun = h.negativeRun || run < 0
+}
+
+// callerRunsWorkload runs three tasks on a pool of one worker and a queue of
+// one: the first occupies the worker, the second fills the queue and the
+// third runs in the submitting goroutine. The second panics.
+func callerRunsWorkload(t *testing.T, p *Pool) {
+	t.Helper()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := p.NewGroup()
+	g.Go(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	g.Go(func() error { panic("queued") })
+	ranInline := false
+	g.Go(func() error {
+		ranInline = true
+		return nil
+	})
+	if !ranInline {
+		t.Fatal("the task submitted to a full queue did not run in the caller")
+	}
+	close(release)
+	if err := g.Wait(); err == nil {
+		t.Fatal("the panic was not reported")
+	}
+}
+
+func TestStatsCounters(t *testing.T) {
+	p := NewPool(WithWorkers(1), WithQueueDepth(1), WithName("counted"))
+	callerRunsWorkload(t, p)
+	// Close waits for the bookkeeping after the last task, so the counters
+	// are final.
+	p.Close()
+
+	st := p.Stats()
+	if st.Name != "counted" {
+		t.Errorf("Name = %q", st.Name)
+	}
+	if st.Submitted != 3 || st.Completed != 3 || st.RanInline != 1 {
+		t.Errorf("submitted %d, completed %d, ran inline %d; want 3, 3 and 1", st.Submitted, st.Completed, st.RanInline)
+	}
+	if st.TotalRun <= 0 || st.MaxWait > st.TotalWait || st.MeanWait() != st.TotalWait/3 {
+		t.Errorf("timings: run %v, wait %v, max wait %v, mean wait %v", st.TotalRun, st.TotalWait, st.MaxWait, st.MeanWait())
+	}
+
+	p = NewPool(WithWorkers(4), WithQueueDepth(256))
+	g := p.NewGroup()
+	for i := 0; i < 100; i++ {
+		g.Go(func() error { return nil })
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if st := p.Stats(); st.Submitted != 100 || st.Completed != 100 || st.RanInline != 0 {
+		t.Errorf("submitted %d, completed %d, ran inline %d; want 100, 100 and 0", st.Submitted, st.Completed, st.RanInline)
+	}
+}
+
+func TestStatsWorkers(t *testing.T) {
+	p := NewPool(WithWorkers(3))
+	defer p.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := p.NewGroup()
+	g.Go(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	st := p.Stats()
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if st.Workers != 3 || st.Busy != 1 || st.Idle != 2 || len(st.WorkerBusy) != 3 {
+		t.Fatalf("workers %d, busy %d, idle %d, %d busy times; want 3, 1, 2 and 3", st.Workers, st.Busy, st.Idle, len(st.WorkerBusy))
+	}
+}
+
+func TestHookSeesEveryTask(t *testing.T) {
+	h := newRecordingHook()
+	p := NewPool(WithWorkers(1), WithQueueDepth(1), WithName("hooked"), WithHook(h))
+	callerRunsWorkload(t, p)
+	if err := p.ParallelFor(100, 10, func(int, int) {}); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	submitted := h.queued["hooked"] + h.inline["hooked"]
+	if h.inline["hooked"] < 1 {
+		t.Errorf("hook saw %d caller-run submissions, want at least 1", h.inline["hooked"])
+	}
+	st := p.Stats()
+	if uint64(submitted) != st.Submitted || uint64(h.inline["hooked"]) != st.RanInline {
+		t.Errorf("hook saw %d submissions, %d inline; stats say %d, %d", submitted, h.inline["hooked"], st.Submitted, st.RanInline)
+	}
+	if h.started["hooked"] != submitted || h.finished["hooked"] != submitted {
+		t.Errorf("hook saw %d submitted, %d started and %d finished tasks", submitted, h.started["hooked"], h.finished["hooked"])
+	}
+	if h.negativeWait || h.negativeRun {
+		t.Error("hook got a negative duration")
+	}
+	if len(h.started) != 1 {
+		t.Errorf("hook saw events for pools %v", h.started)
+	}
+}
+
+func TestTraceRegions(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("a trace is already running")
+	}
+	p := NewPool(WithWorkers(2), WithName("traced"))
+	defer p.Close()
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skip(err)
+	}
+	err := p.ParallelFor(64, 1, func(int, int) {}, WithLabel("routines-test-label"))
+	trace.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("routines-test-label")) {
+		t.Fatal("the trace has no region named after the ParallelFor label")
+	}
+}
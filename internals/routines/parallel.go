@@ -0,0 +1,142 @@
+package routines
+
+import "sync/atomic"
+
+// Schedule decides how ParallelFor hands out ranges to workers.
+type Schedule int
+
+const (
+	// Static splits the range into one equal chunk per worker up front.
+	// It has the least overhead and suits loops whose iterations cost the
+	// same, like the element-wise matrix operations.
+	Static Schedule = iota
+	// Dynamic hands out grain sized chunks on demand, which balances loops
+	// whose iterations vary in cost.
+	Dynamic
+)
+
+type forConfig struct {
+	schedule Schedule
+	label    string
+}
+
+// ForOption configures ParallelFor and ParallelReduce.
+type ForOption func(*forConfig)
+
+func WithSchedule(s Schedule) ForOption {
+	return func(c *forConfig) { c.schedule = s }
+}
+
+// WithLabel names the chunks in execution traces.
+func WithLabel(label string) ForOption {
+	return func(c *forConfig) { c.label = label }
+}
+
+// ParallelFor runs fn over [0, n) on GlobalPool.
+func ParallelFor(n, grain int, fn func(start, end int), opts ...ForOption) error {
+	return GlobalPool.ParallelFor(n, grain, fn, opts...)
+}
+
+// ParallelFor calls fn on disjoint ranges covering [0, n), each at least
+// grain long except possibly the last, and returns once all of them have
+// finished. Ranges that fit in a single grain, or a pool of one worker, run
+// inline. The first panic in fn is returned as a *PanicError.
+func (p *Pool) ParallelFor(n, grain int, fn func(start, end int), opts ...ForOption) error {
+	if n <= 0 {
+		return nil
+	}
+	if p.Closed() {
+		return ErrPoolClosed
+	}
+	cfg := forConfig{label: p.name}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if grain < 1 {
+		grain = 1
+	}
+
+	numChunks := (n + grain - 1) / grain
+	workers := p.Size()
+	if numChunks < 2 || workers < 2 {
+		return run(func() error { fn(0, n); return nil })
+	}
+
+	group := p.NewNamedGroup(cfg.label)
+	if cfg.schedule == Dynamic {
+		var next atomic.Int64
+		loop := func() error {
+			for {
+				start := int(next.Add(int64(grain))) - grain
+				if start >= n {
+					return nil
+				}
+				fn(start, min(start+grain, n))
+			}
+		}
+		for i := 1; i < min(workers, numChunks); i++ {
+			group.Go(loop)
+		}
+		// The caller takes part instead of sitting idle in Wait.
+		group.runInline(loop)
+		return group.Wait()
+	}
+
+	// Chunks stay a multiple of grain so that every range starts on a
+	// grain boundary, which ParallelReduceOn relies on.
+	numChunks = min(numChunks, workers)
+	chunkSize := (n + numChunks - 1) / numChunks
+	chunkSize = (chunkSize + grain - 1) / grain * grain
+	for start := chunkSize; start < n; start += chunkSize {
+		group.Go(func() error {
+			fn(start, min(start+chunkSize, n))
+			return nil
+		})
+	}
+	group.runInline(func() error {
+		fn(0, min(chunkSize, n))
+		return nil
+	})
+	return group.Wait()
+}
+
+// ParallelReduce is ParallelReduceOn with GlobalPool.
+func ParallelReduce[T any](n, grain int, identity T, mapFn func(start, end int) T, combine func(a, b T) T, opts ...ForOption) (T, error) {
+	return ParallelReduceOn(GlobalPool, n, grain, identity, mapFn, combine, opts...)
+}
+
+// ParallelReduceOn maps every range chosen by ParallelFor to a partial
+// result and folds the partials, in range order, with combine starting from
+// identity. Keeping the order makes floating point sums reproducible for a
+// given grain and pool size.
+func ParallelReduceOn[T any](p *Pool, n, grain int, identity T, mapFn func(start, end int) T, combine func(a, b T) T, opts ...ForOption) (T, error) {
+	if grain < 1 {
+		grain = 1
+	}
+	if n <= 0 {
+		return identity, nil
+	}
+	// Partials are indexed by grain sized block so that either schedule
+	// can record them without locking.
+	numBlocks := (n + grain - 1) / grain
+	partials := make([]T, numBlocks)
+	filled := make([]bool, numBlocks)
+	err := p.ParallelFor(n, grain, func(start, end int) {
+		for s := start; s < end; s += grain {
+			block := s / grain
+			partials[block] = mapFn(s, min(s+grain, end))
+			filled[block] = true
+		}
+	}, opts...)
+	if err != nil {
+		return identity, err
+	}
+
+	acc := identity
+	for i, partial := range partials {
+		if filled[i] {
+			acc = combine(acc, partial)
+		}
+	}
+	return acc, nil
+}
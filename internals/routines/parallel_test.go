@@ -0,0 +1,243 @@
+package routines
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var schedules = []struct {
+	name     string
+	schedule Schedule
+}{
+	{"static", Static},
+	{"dynamic", Dynamic},
+}
+
+// coverage records how often every index in [0, n) was visited.
+type coverage struct {
+	mu     sync.Mutex
+	counts []int
+	ranges int
+}
+
+func newCoverage(n int) *coverage {
+	return &coverage{counts: make([]int, max(n, 0))}
+}
+
+func (c *coverage) visit(start, end int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ranges++
+	for i := start; i < end; i++ {
+		c.counts[i]++
+	}
+}
+
+func (c *coverage) check(t *testing.T) {
+	t.Helper()
+	for i, count := range c.counts {
+		if count != 1 {
+			t.Fatalf("index %d visited %d times", i, count)
+		}
+	}
+}
+
+func TestParallelFor(t *testing.T) {
+	p := NewPool(WithWorkers(4))
+	defer p.Close()
+
+	tests := []struct {
+		name   string
+		n      int
+		grain  int
+		ranges int // expected number of calls to fn, or 0 to skip the check
+	}{
+		{"empty", 0, 1, 0},
+		{"negative", -5, 1, 0},
+		{"fewer indices than workers", 3, 1, 0},
+		{"single index", 1, 1, 1},
+		{"grain larger than n", 10, 100, 1},
+		{"grain equal to n", 10, 10, 1},
+		{"zero grain", 100, 0, 0},
+		{"negative grain", 100, -3, 0},
+		{"uneven", 1001, 7, 0},
+		{"many chunks", 10000, 1, 0},
+	}
+	for _, tt := range tests {
+		for _, sc := range schedules {
+			t.Run(tt.name+"/"+sc.name, func(t *testing.T) {
+				c := newCoverage(tt.n)
+				if err := p.ParallelFor(tt.n, tt.grain, c.visit, WithSchedule(sc.schedule)); err != nil {
+					t.Fatal(err)
+				}
+				c.check(t)
+				if tt.n <= 0 && c.ranges != 0 {
+					t.Fatalf("fn called %d times for n = %d", c.ranges, tt.n)
+				}
+				if tt.ranges > 0 && c.ranges != tt.ranges {
+					t.Fatalf("fn called %d times, want %d", c.ranges, tt.ranges)
+				}
+			})
+		}
+	}
+}
+
+func TestParallelForStaticRangesFollowGrain(t *testing.T) {
+	p := NewPool(WithWorkers(4))
+	defer p.Close()
+
+	var mu sync.Mutex
+	var bad [][2]int
+	err := p.ParallelFor(1000, 7, func(start, end int) {
+		if start%7 != 0 || (end-start < 7 && end != 1000) {
+			mu.Lock()
+			bad = append(bad, [2]int{start, end})
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) > 0 {
+		t.Fatalf("ranges not aligned to the grain: %v", bad)
+	}
+}
+
+func TestParallelForSingleWorkerRunsInline(t *testing.T) {
+	p := NewPool(WithWorkers(1))
+	defer p.Close()
+
+	c := newCoverage(100)
+	if err := p.ParallelFor(100, 1, c.visit); err != nil {
+		t.Fatal(err)
+	}
+	c.check(t)
+	if c.ranges != 1 {
+		t.Fatalf("fn called %d times on a single worker pool, want 1", c.ranges)
+	}
+}
+
+func TestParallelForClosedPool(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	p.Close()
+	var called atomic.Bool
+	if err := p.ParallelFor(10, 1, func(int, int) { called.Store(true) }); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("ParallelFor error = %v, want ErrPoolClosed", err)
+	}
+	if called.Load() {
+		t.Fatal("fn ran on a closed pool")
+	}
+	// Nothing to do is not an error, closed or not.
+	if err := p.ParallelFor(0, 1, func(int, int) {}); err != nil {
+		t.Fatalf("ParallelFor(0) error = %v", err)
+	}
+}
+
+func TestParallelForPanic(t *testing.T) {
+	p := NewPool(WithWorkers(4))
+	defer p.Close()
+
+	for _, tt := range []struct {
+		name     string
+		n, grain int
+	}{
+		{"inline", 10, 100},
+		{"in a worker", 1000, 10},
+	} {
+		for _, sc := range schedules {
+			t.Run(tt.name+"/"+sc.name, func(t *testing.T) {
+				err := p.ParallelFor(tt.n, tt.grain, func(start, end int) {
+					if start <= tt.n-1 && tt.n-1 < end {
+						panic("last chunk")
+					}
+				}, WithSchedule(sc.schedule))
+				var panicErr *PanicError
+				if !errors.As(err, &panicErr) {
+					t.Fatalf("ParallelFor error = %v, want a *PanicError", err)
+				}
+				if panicErr.Value != "last chunk" || len(panicErr.Stack) == 0 {
+					t.Fatalf("PanicError = %v with %d byte stack", panicErr.Value, len(panicErr.Stack))
+				}
+			})
+		}
+	}
+
+	// The pool keeps working after a panic.
+	c := newCoverage(100)
+	if err := p.ParallelFor(100, 1, c.visit); err != nil {
+		t.Fatal(err)
+	}
+	c.check(t)
+}
+
+func TestParallelReduceOrder(t *testing.T) {
+	p := NewPool(WithWorkers(4))
+	defer p.Close()
+
+	// Concatenation is not commutative, so any reordering of the partials
+	// shows up in the result.
+	want := ""
+	for i := 0; i < 500; i++ {
+		want += fmt.Sprintf("%d,", i)
+	}
+	for _, sc := range schedules {
+		for _, grain := range []int{0, 1, 7, 64, 1000} {
+			t.Run(fmt.Sprintf("%s/grain=%d", sc.name, grain), func(t *testing.T) {
+				for run := 0; run < 5; run++ {
+					got, err := ParallelReduceOn(p, 500, grain, "", func(start, end int) string {
+						s := ""
+						for i := start; i < end; i++ {
+							s += fmt.Sprintf("%d,", i)
+						}
+						return s
+					}, func(a, b string) string { return a + b }, WithSchedule(sc.schedule))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got != want {
+						t.Fatalf("reduce out of order: %.60s...", got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParallelReduceFloatSumIsReproducible(t *testing.T) {
+	p := NewPool(WithWorkers(4))
+	defer p.Close()
+
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = 1 / float64(i+1)
+	}
+	sum := func() float64 {
+		s, err := ParallelReduceOn(p, len(values), 37, 0.0, func(start, end int) float64 {
+			s := 0.0
+			for _, v := range values[start:end] {
+				s += v
+			}
+			return s
+		}, func(a, b float64) float64 { return a + b }, WithSchedule(Dynamic))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	first := sum()
+	for i := 0; i < 20; i++ {
+		if got := sum(); got != first {
+			t.Fatalf("sum changed between runs: %v != %v", got, first)
+		}
+	}
+}
+
+func TestParallelReduceEmpty(t *testing.T) {
+	got, err := ParallelReduceOn(GlobalPool, 0, 1, 42, func(int, int) int { return 1 }, func(a, b int) int { return a + b })
+	if err != nil || got != 42 {
+		t.Fatalf("ParallelReduceOn(0) = %v, %v, want the identity", got, err)
+	}
+}
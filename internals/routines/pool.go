@@ -1,54 +1,375 @@
 package routines
 
 import (
+	"errors"
+	"fmt"
 	"runtime"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var GlobalPool *Pool
 
 func init() {
-	GlobalPool = NewPool(runtime.NumCPU())
+	GlobalPool = NewPool(WithName("global"))
 }
 
+// ErrPoolClosed is returned for work submitted after Close.
+var ErrPoolClosed = errors.New("Pool is closed")
+
 // Pool manages a collection of workers.
 type Pool struct {
-	Tasks   chan func()
-	workers []*Worker
+	Tasks chan func()
+	name  string
 
+	// mu guards workers and closed. Submissions hold the read lock only
+	// while queueing, so Close can take the write lock to stop new work.
+	mu       sync.RWMutex
+	workers  []*Worker
+	nextID   int
+	closed   bool
 	workerWg sync.WaitGroup
-	taskWg   sync.WaitGroup
+
+	// inflight counts tasks submitted but not yet finished.
+	inflight sync.WaitGroup
+
+	// legacy is the group behind AddTask and WaitAll. WaitAll swaps in a
+	// fresh one so that it only reports tasks added before it was called;
+	// legacyMu keeps a task from being counted in a group already swapped
+	// out.
+	legacyMu sync.RWMutex
+	legacy   *Group
+
+	metrics poolMetrics
+	hook    Hook
 }
 
-func NewPool(numWorkers int) *Pool {
-	p := &Pool{
-		Tasks: make(chan func(), numWorkers*2),
+type poolConfig struct {
+	workers    int
+	queueDepth int
+	name       string
+	hook       Hook
+}
+
+// Option configures a Pool created by NewPool.
+type Option func(*poolConfig)
+
+// WithWorkers sets the number of worker goroutines. The default is
+// runtime.NumCPU().
+func WithWorkers(n int) Option {
+	return func(c *poolConfig) { c.workers = n }
+}
+
+// WithQueueDepth sets how many tasks may wait in the queue before
+// submitters start running tasks themselves. The default is twice the
+// number of workers.
+func WithQueueDepth(n int) Option {
+	return func(c *poolConfig) { c.queueDepth = n }
+}
+
+// WithName labels the pool in stats and traces.
+func WithName(name string) Option {
+	return func(c *poolConfig) { c.name = name }
+}
+
+// PanicError is returned in place of a task that panicked. It carries the
+// recovered value and the stack of the goroutine at the time of the panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// Unwrap exposes the panic value when it was itself an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
 	}
+	return nil
+}
 
-	for i := 1; i <= numWorkers; i++ {
-		worker := NewWorker(i, p.Tasks)
-		p.workers = append(p.workers, worker)
-		worker.Start(&p.workerWg)
+func NewPool(opts ...Option) *Pool {
+	cfg := poolConfig{
+		workers: runtime.NumCPU(),
+		name:    "pool",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
+	if cfg.workers < 1 {
+		cfg.workers = 1
+	}
+	if cfg.queueDepth <= 0 {
+		cfg.queueDepth = cfg.workers * 2
+	}
+
+	p := &Pool{
+		Tasks: make(chan func(), cfg.queueDepth),
+		name:  cfg.name,
+		hook:  cfg.hook,
+	}
+	p.legacy = p.NewGroup()
+	p.addWorkers(cfg.workers)
 
 	return p
 }
 
-func (p *Pool) AddTask(task func()) {
-	p.taskWg.Add(1)
-	p.Tasks <- func() {
-		defer p.taskWg.Done()
-		task()
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Size returns the current number of workers.
+func (p *Pool) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.workers)
+}
+
+func (p *Pool) Closed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.closed
+}
+
+// Resize grows or shrinks the pool to n workers. Workers that are removed
+// finish their current task first.
+func (p *Pool) Resize(n int) error {
+	if n < 1 {
+		return errors.New("Pool needs at least one worker")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	if n > len(p.workers) {
+		p.addWorkers(n - len(p.workers))
+		return nil
 	}
+	for _, worker := range p.workers[n:] {
+		worker.Stop()
+	}
+	p.workers = p.workers[:n]
+	return nil
 }
 
-func (p *Pool) WaitAll() {
-	p.taskWg.Wait()
+// addWorkers starts n more workers. The caller must hold mu or have sole
+// access to p.
+func (p *Pool) addWorkers(n int) {
+	for i := 0; i < n; i++ {
+		p.nextID++
+		worker := NewWorker(p.nextID, p.Tasks)
+		p.workers = append(p.workers, worker)
+		worker.Start(&p.workerWg)
+	}
 }
 
-func (p *Pool) StopAll() {
+// Close stops accepting work, waits for every queued and running task to
+// finish and then stops the workers. It must not be called from inside a
+// task on the same pool.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	p.inflight.Wait()
+
+	p.mu.Lock()
 	for _, worker := range p.workers {
 		worker.Stop()
 	}
+	p.workers = nil
+	p.mu.Unlock()
 	p.workerWg.Wait()
+	return nil
+}
+
+// submit queues task, or runs it in the calling goroutine when the queue is
+// full so that a worker waiting on nested work never blocks the pool. The
+// label names the trace region the task runs in.
+func (p *Pool) submit(task func(), label string) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	p.inflight.Add(1)
+	submitted := time.Now()
+	wrapped := func() {
+		defer p.inflight.Done()
+		p.runTask(task, label, time.Since(submitted))
+	}
+	queued := false
+	select {
+	case p.Tasks <- wrapped:
+		queued = true
+	default:
+	}
+	p.mu.RUnlock()
+
+	p.metrics.submitted.Add(1)
+	if p.hook != nil {
+		p.hook.TaskSubmitted(p.name, queued)
+	}
+	if !queued {
+		p.metrics.inline.Add(1)
+		wrapped()
+	}
+	return nil
+}
+
+// AddTask queues a task on the pool-wide group.
+//
+// Deprecated: use NewGroup, which lets concurrent callers wait for their own
+// tasks only.
+func (p *Pool) AddTask(task func()) error {
+	p.legacyMu.RLock()
+	g := p.legacy
+	g.pending.Add(1)
+	p.legacyMu.RUnlock()
+	return g.dispatch(func() error {
+		task()
+		return nil
+	})
+}
+
+// WaitAll blocks until every task added with AddTask before the call has
+// finished and returns the first panic among them, if any. Tasks added while
+// it waits are left to the next WaitAll, errors included.
+//
+// Deprecated: use NewGroup and Group.Wait.
+func (p *Pool) WaitAll() error {
+	p.legacyMu.Lock()
+	g := p.legacy
+	p.legacy = p.NewGroup()
+	p.legacyMu.Unlock()
+	return g.Wait()
+}
+
+// StopAll closes the pool.
+//
+// Deprecated: use Close, which also reports a second close.
+func (p *Pool) StopAll() {
+	p.Close()
+}
+
+// NewGroup returns an empty task group on this pool.
+func (p *Pool) NewGroup() *Group {
+	return p.NewNamedGroup(p.name)
+}
+
+// NewNamedGroup returns an empty task group whose tasks show up under name
+// in execution traces.
+func (p *Pool) NewNamedGroup(name string) *Group {
+	return &Group{
+		pool: p,
+		name: name,
+		done: make(chan struct{}),
+	}
+}
+
+// Group is a set of tasks whose completion can be waited for independently
+// of anything else running on the pool, in the manner of errgroup.
+//
+// Groups may be used from inside pool tasks. Go runs the task in the calling
+// goroutine when the queue is full, and Wait runs queued tasks while it
+// waits, so a worker blocked on a nested group never stalls the pool.
+type Group struct {
+	pool    *Pool
+	name    string
+	pending atomic.Int64
+
+	mu  sync.Mutex
+	err error
+	// done is closed, and replaced for any later tasks, each time pending
+	// drops to zero, which wakes every goroutine in Wait.
+	done chan struct{}
+}
+
+// Go queues task on the group's pool. A returned error or a panic inside the
+// task is reported by Wait, as is ErrPoolClosed if the pool no longer
+// accepts work.
+func (g *Group) Go(task func() error) {
+	g.submit(task)
+}
+
+func (g *Group) submit(task func() error) error {
+	g.pending.Add(1)
+	return g.dispatch(task)
+}
+
+// dispatch queues a task already counted in pending.
+func (g *Group) dispatch(task func() error) error {
+	err := g.pool.submit(func() {
+		g.finish(run(task))
+	}, g.name)
+	if err != nil {
+		g.finish(err)
+	}
+	return err
+}
+
+// Wait blocks until every task in the group has finished and returns the
+// first error, which is a *PanicError if the task panicked. While waiting it
+// helps by running tasks from the pool queue.
+func (g *Group) Wait() error {
+	for {
+		// done must be read before pending: the finish that brings pending
+		// to zero afterwards closes this channel or an earlier one.
+		g.mu.Lock()
+		done := g.done
+		g.mu.Unlock()
+		if g.pending.Load() == 0 {
+			break
+		}
+		select {
+		case task := <-g.pool.Tasks:
+			task()
+		case <-done:
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// runInline runs task in the calling goroutine as part of the group.
+func (g *Group) runInline(task func() error) {
+	g.pending.Add(1)
+	g.finish(run(task))
+}
+
+func (g *Group) finish(err error) {
+	if err != nil {
+		g.mu.Lock()
+		if g.err == nil {
+			g.err = err
+		}
+		g.mu.Unlock()
+	}
+	if g.pending.Add(-1) == 0 {
+		g.mu.Lock()
+		close(g.done)
+		g.done = make(chan struct{})
+		g.mu.Unlock()
+	}
+}
+
+// run calls task, turning a panic into a *PanicError.
+func run(task func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return task()
 }
@@ -0,0 +1,272 @@
+package routines
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitAllReportsPanics(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	defer p.Close()
+
+	var ran atomic.Int64
+	for i := 0; i < 10; i++ {
+		p.AddTask(func() { ran.Add(1) })
+	}
+	p.AddTask(func() { panic("boom") })
+	var panicErr *PanicError
+	if err := p.WaitAll(); !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("WaitAll error = %v, want a *PanicError", err)
+	}
+	if ran.Load() != 10 {
+		t.Fatalf("%d tasks ran, want 10", ran.Load())
+	}
+	if err := p.WaitAll(); err != nil {
+		t.Fatalf("second WaitAll error = %v, want nil", err)
+	}
+}
+
+func TestWaitAllKeepsErrorsOfLaterTasks(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	defer p.Close()
+
+	release := make(chan struct{})
+	added := make(chan struct{})
+	first := p.legacy
+	p.AddTask(func() {
+		// Wait for the first WaitAll to start, so that the task added here
+		// belongs to the next one.
+		for {
+			p.legacyMu.RLock()
+			swapped := p.legacy != first
+			p.legacyMu.RUnlock()
+			if swapped {
+				break
+			}
+			runtime.Gosched()
+		}
+		p.AddTask(func() {
+			<-release
+			panic("late")
+		})
+		close(added)
+	})
+	if err := p.WaitAll(); err != nil {
+		t.Fatalf("first WaitAll error = %v, want nil", err)
+	}
+	<-added
+	close(release)
+	var panicErr *PanicError
+	if err := p.WaitAll(); !errors.As(err, &panicErr) || panicErr.Value != "late" {
+		t.Fatalf("second WaitAll error = %v, want the late panic", err)
+	}
+}
+
+func TestAddTaskOnClosedPool(t *testing.T) {
+	p := NewPool(WithWorkers(1))
+	p.Close()
+	if err := p.AddTask(func() {}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("AddTask error = %v, want ErrPoolClosed", err)
+	}
+	if err := p.WaitAll(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("WaitAll error = %v, want ErrPoolClosed", err)
+	}
+}
+
+// waitOrFail fails the test if done is not closed within a few seconds.
+func waitOrFail(t *testing.T, done <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not finish", what)
+	}
+}
+
+func TestGroupNestedSubmission(t *testing.T) {
+	// Every worker blocks in a nested Wait, and the queue is tiny, so this
+	// only finishes if waiting helps with the queue and Go runs tasks
+	// inline when it is full.
+	p := NewPool(WithWorkers(2), WithQueueDepth(1))
+	defer p.Close()
+
+	var leaves atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		outer := p.NewGroup()
+		for i := 0; i < 8; i++ {
+			outer.Go(func() error {
+				inner := p.NewGroup()
+				for j := 0; j < 8; j++ {
+					inner.Go(func() error {
+						leaves.Add(1)
+						return nil
+					})
+				}
+				return inner.Wait()
+			})
+		}
+		if err := outer.Wait(); err != nil {
+			t.Error(err)
+		}
+	}()
+	waitOrFail(t, done, "nested groups")
+	if leaves.Load() != 64 {
+		t.Fatalf("%d nested tasks ran, want 64", leaves.Load())
+	}
+}
+
+func TestGroupConcurrentWait(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	defer p.Close()
+
+	for run := 0; run < 50; run++ {
+		g := p.NewGroup()
+		release := make(chan struct{})
+		g.Go(func() error {
+			<-release
+			return nil
+		})
+		const waiters = 8
+		var returned sync.WaitGroup
+		returned.Add(waiters)
+		for i := 0; i < waiters; i++ {
+			go func() {
+				defer returned.Done()
+				if err := g.Wait(); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		// Let the waiters block before the only task finishes, so that a
+		// single wake-up cannot reach all of them.
+		time.Sleep(time.Millisecond)
+		close(release)
+		done := make(chan struct{})
+		go func() {
+			returned.Wait()
+			close(done)
+		}()
+		waitOrFail(t, done, "concurrent Wait calls")
+	}
+}
+
+func TestGroupReuseAfterWait(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	defer p.Close()
+
+	g := p.NewGroup()
+	var ran atomic.Int64
+	for round := 1; round <= 3; round++ {
+		for i := 0; i < 10; i++ {
+			g.Go(func() error {
+				ran.Add(1)
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			t.Fatal(err)
+		}
+		if got := ran.Load(); got != int64(10*round) {
+			t.Fatalf("after round %d, %d tasks ran, want %d", round, got, 10*round)
+		}
+	}
+}
+
+func TestResizeWhileRunning(t *testing.T) {
+	p := NewPool(WithWorkers(2))
+	defer p.Close()
+
+	var ran atomic.Int64
+	g := p.NewGroup()
+	for i := 0; i < 200; i++ {
+		g.Go(func() error {
+			time.Sleep(50 * time.Microsecond)
+			ran.Add(1)
+			return nil
+		})
+	}
+	for _, size := range []int{6, 1, 4, 2} {
+		if err := p.Resize(size); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Size(); got != size {
+			t.Fatalf("Size() = %d after Resize(%d)", got, size)
+		}
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() != 200 {
+		t.Fatalf("%d tasks ran, want 200", ran.Load())
+	}
+	if err := p.Resize(0); err == nil {
+		t.Fatal("Resize(0) succeeded")
+	}
+}
+
+func TestCloseFinishesQueuedWork(t *testing.T) {
+	p := NewPool(WithWorkers(1), WithQueueDepth(16))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var ran atomic.Int64
+	g := p.NewGroup()
+	g.Go(func() error {
+		close(started)
+		<-release
+		ran.Add(1)
+		return nil
+	})
+	<-started
+	for i := 0; i < 10; i++ {
+		g.Go(func() error {
+			ran.Add(1)
+			return nil
+		})
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		if err := p.Close(); err != nil {
+			t.Error(err)
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a task was still running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	waitOrFail(t, closed, "Close")
+	if ran.Load() != 11 {
+		t.Fatalf("%d tasks ran before Close returned, want 11", ran.Load())
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	late := p.NewGroup()
+	late.Go(func() error {
+		t.Error("task ran on a closed pool")
+		return nil
+	})
+	if err := late.Wait(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Wait on a closed pool = %v, want ErrPoolClosed", err)
+	}
+	if err := p.Resize(2); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Resize on a closed pool = %v, want ErrPoolClosed", err)
+	}
+	if err := p.Close(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("second Close = %v, want ErrPoolClosed", err)
+	}
+	if st := p.Stats(); !st.Closed || st.Workers != 0 || p.Size() != 0 {
+		t.Fatalf("closed pool stats: closed %v, %d workers", st.Closed, st.Workers)
+	}
+}
@@ -1,18 +1,26 @@
 package routines
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 type Worker struct {
 	ID   int
 	Task chan func()
-	Quit chan bool
+	Quit chan struct{}
+
+	busy     atomic.Bool
+	busyTime atomic.Int64
+	stopOnce sync.Once
 }
 
 func NewWorker(id int, task chan func()) *Worker {
 	return &Worker{
 		ID:   id,
 		Task: task,
-		Quit: make(chan bool),
+		Quit: make(chan struct{}),
 	}
 }
 
@@ -23,7 +31,11 @@ func (w *Worker) Start(wg *sync.WaitGroup) {
 		for {
 			select {
 			case task := <-w.Task:
+				w.busy.Store(true)
+				start := time.Now()
 				task()
+				w.busyTime.Add(int64(time.Since(start)))
+				w.busy.Store(false)
 			case <-w.Quit:
 				return
 			}
@@ -31,17 +43,20 @@ func (w *Worker) Start(wg *sync.WaitGroup) {
 	}()
 }
 
+// Stop asks the worker to exit once its current task, if any, is done. It
+// does not wait and is safe to call more than once.
 func (w *Worker) Stop() {
-	go func() {
-		w.Quit <- true
-	}()
+	w.stopOnce.Do(func() {
+		close(w.Quit)
+	})
 }
 
+// BusyTime is the total time the worker has spent running tasks.
+func (w *Worker) BusyTime() time.Duration {
+	return time.Duration(w.busyTime.Load())
+}
+
+// IsIdle reports whether the worker is between tasks.
 func (w *Worker) IsIdle() bool {
-	select {
-	case <-w.Task:
-		return false
-	default:
-		return true
-	}
+	return !w.busy.Load()
 }